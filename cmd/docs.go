@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+	"noculture/rpn/core"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var docs = &cobra.Command{
+	Use:   "docs",
+	Short: "Print a reference of the commands",
+	Long: `docs prints a Markdown reference of the built-in commands, grouped as in rpn --help, with the types each one
+pops and pushes, its aliases and examples whose results are worked out by running them. The words of any plugins
+are listed last.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		calc := core.New()
+		if err := calc.LoadPlugins(); err != nil {
+			fmt.Fprintf(os.Stderr, "rpn: %v\n", err)
+		}
+		fmt.Print(calc.Docs())
+	},
+}
+
+func init() {
+	Register(docs)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"noculture/rpn/core"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var fmtWrite = false
+var fmtDiff = false
+var format = &cobra.Command{
+	Use:   "fmt [path...]",
+	Short: "Format rpn scripts",
+	Long: `fmt formats the given scripts (or the *.rpn files under the given directories), or standard input if there are none.
+By default the formatted scripts are printed. Commands are separated by single spaces, aliases are replaced by the
+command they stand for, inside blocks too, the bodies of test: blocks and the lines of blocks spread over several lines
+are indented and comments are kept.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ok, err := core.Fmt(args, fmtWrite, fmtDiff)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	format.Flags().BoolVarP(&fmtWrite, "write", "w", false, "Write the result to the source file instead of printing it")
+	format.Flags().BoolVarP(&fmtDiff, "diff", "d", false, "Print a diff instead of the formatted script")
+	Register(format)
+}
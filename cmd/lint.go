@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+	"noculture/rpn/core"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var lint = &cobra.Command{
+	Use:     "lint [path...]",
+	Aliases: []string{"check"},
+	Short:   "Check rpn scripts for mistakes without running them",
+	Long: `lint walks the given scripts (or the *.rpn files under the given directories) and simulates them on a stack of types.
+It reports stack underflows, type mismatches, unknown commands and unreachable code, and infers the stack effect of each macro.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ok, err := core.Lint(args)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	Register(lint)
+}
@@ -3,24 +3,45 @@ package cmd
 import (
 	"fmt"
 	"noculture/rpn/core"
-	"noculture/rpn/help"
 	"os"
 
 	"github.com/spf13/cobra"
 )
 
 var interactive = false
+var noOpt = false
+var sandbox = false
+var allowImport []string
+var jobs = 0
 var root = &cobra.Command{
 	Use:   "rpn",
 	Short: "A reverse polish notation calculator",
+	Args:  cobra.ArbitraryArgs,
 	Long: fmt.Sprintf(`rpn is a cli tool that brings the power and flexibility of Reverse Polish Notation to your terminal.
 						Command List:
-						%v`, help.COMMANDHELP),
+						%v`, core.Help()),
 	Run: func(cmd *cobra.Command, args []string) {
+		calc := core.New()
+		if noOpt {
+			calc.DisableOptimizations()
+		}
+		if sandbox {
+			if err := calc.Sandbox(allowImport...); err != nil {
+				fmt.Fprintf(os.Stderr, "rpn: %v\n", err)
+				os.Exit(1)
+			}
+		} else if err := calc.LoadPlugins(); err != nil {
+			fmt.Fprintf(os.Stderr, "rpn: %v\n", err)
+		}
+		run := func() error { return calc.Calculate(args) }
 		if interactive {
-			core.Repl()
-		} else {
-			core.Calculate(args)
+			run = calc.Repl
+		} else if jobs > 0 {
+			run = func() error { return calc.CalculateBatch(args, jobs) }
+		}
+		if err := run(); err != nil {
+			fmt.Fprintf(os.Stderr, "rpn: %v\n", err)
+			os.Exit(1)
 		}
 	},
 }
@@ -38,4 +59,8 @@ func Execute() {
 
 func init() {
 	root.Flags().BoolVarP(&interactive, "interactive", "i", false, "Lauch interactive mode")
+	root.PersistentFlags().BoolVar(&noOpt, "no-opt", false, "Run macros without optimising them")
+	root.Flags().BoolVar(&sandbox, "sandbox", false, "Run without exit, plugins, ~/.rpnrc, unseeded rand or imports")
+	root.Flags().IntVarP(&jobs, "jobs", "j", 0, "Run each line of stdin as a calculation of its own, on N goroutines")
+	root.Flags().StringSliceVar(&allowImport, "allow-import", nil, "Directories a sandbox can import scripts from")
 }
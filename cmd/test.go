@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"noculture/rpn/core"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var testFormat = "human"
+var testVerbose = false
+var test = &cobra.Command{
+	Use:   "test [dir]",
+	Short: "Run the tests in *_test.rpn files",
+	Long: `test discovers the *_test.rpn files under dir (the current directory by default) and runs them.
+Each "test: name" line starts a test that runs in a fresh calculator, after the lines that come before the first test.
+A test fails if any of its commands fail, e.g. an assert, assert= or assert~.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		calc := core.New()
+		if noOpt {
+			calc.DisableOptimizations()
+		}
+		ok, err := calc.Test(dir, testFormat, testVerbose)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	test.Flags().StringVarP(&testFormat, "format", "f", "human", "Output format: human, json or tap")
+	test.Flags().BoolVarP(&testVerbose, "verbose", "v", false, "Report passing tests too")
+	Register(test)
+}
@@ -0,0 +1,619 @@
+package core
+
+import (
+	"math"
+	"math/big"
+)
+
+// guardBits -> extra bits carried through intermediate results so that the final rounding is correct
+const guardBits = 32
+
+var log2of10 = math.Log2(10)
+
+func precisionBits(digits int) uint {
+	return uint(math.Ceil(float64(digits) * log2of10))
+}
+
+// bigFloat -> a number kept to the precision set with prec
+type bigFloat struct {
+	value *big.Float
+}
+
+func (x bigFloat) Kind() Kind {
+	return BigFloat
+}
+
+// String -> show the digits of the precision in dec mode, or the number as a float64 in the other modes
+func (x bigFloat) show(c *Calculator) string {
+	if c.mode == DEC {
+		return c.formatBig(x.value)
+	}
+	number, _ := x.value.Float64()
+	return float(number).show(c)
+}
+
+func (x bigFloat) Equal(other Value) bool {
+	return numericEqual(x, other)
+}
+
+func parseBig(item, mode string, digits int) (*big.Float, bool) {
+	if mode != DEC {
+		number, err := parseNumber(item, mode)
+		return new(big.Float).SetPrec(precisionBits(digits)).SetFloat64(number), err == nil
+	}
+	x, _, err := big.ParseFloat(item, 10, precisionBits(digits), big.ToNearestEven)
+	return x, err == nil
+}
+
+func (c *Calculator) formatBig(x *big.Float) string {
+	digits := c.precision
+	if digits == 0 {
+		digits = int(float64(x.Prec()) / log2of10)
+	}
+	return x.Text('g', digits)
+}
+
+// handleBig -> run a command at the current precision, returning false for the commands it doesn't cover
+func (c *Calculator) handleBig(token Token) (handled bool) {
+	if c.precision == 0 {
+		return false
+	}
+	prec := precisionBits(c.precision)
+	work := prec + guardBits
+	defer func() {
+		// results that float64 would make NaN can't be represented by a big.Float
+		if r := recover(); r != nil {
+			if _, ok := r.(big.ErrNaN); !ok {
+				panic(r)
+			}
+			c.push(float(math.NaN()))
+			handled = true
+		}
+	}()
+
+	switch token.Type {
+	case PI:
+		c.pushBig(bigPi(work))
+	case E:
+		c.pushBig(bigExp(bigInt(1, work), work))
+	case PLUS:
+		op1 := c.popBig(PLUS)
+		op2 := c.popBig(PLUS)
+		c.pushBig(bigNew(work).Add(op2, op1))
+	case MINUS:
+		op1 := c.popBig(MINUS)
+		op2 := c.popBig(MINUS)
+		c.pushBig(bigNew(work).Sub(op2, op1))
+	case MULTIPLY:
+		op1 := c.popBig(MULTIPLY)
+		op2 := c.popBig(MULTIPLY)
+		c.pushBig(bigNew(work).Mul(op2, op1))
+	case DIVIDE:
+		op1 := c.popBig(DIVIDE)
+		op2 := c.popBig(DIVIDE)
+		c.pushBig(bigNew(work).Quo(op2, op1))
+	case DIVINT:
+		op1 := c.popBig(DIVINT)
+		op2 := c.popBig(DIVINT)
+		c.pushBig(bigTrunc(bigNew(work).Quo(op2, op1), work))
+	case MOD:
+		op1 := c.popBig(MOD)
+		op2 := c.popBig(MOD)
+		c.pushBig(bigMod(op2, op1, work))
+	case DECR:
+		op1 := c.popBig(DECR)
+		c.pushBig(bigNew(work).Sub(op1, bigInt(1, work)))
+	case INCR:
+		op1 := c.popBig(INCR)
+		c.pushBig(bigNew(work).Add(op1, bigInt(1, work)))
+
+	case LT:
+		op1 := c.popBig(LT)
+		op2 := c.popBig(LT)
+		c.push(boolean(op2.Cmp(op1) < 0))
+	case LTOREQ:
+		op1 := c.popBig(LTOREQ)
+		op2 := c.popBig(LTOREQ)
+		c.push(boolean(op2.Cmp(op1) <= 0))
+	case NOTEQ:
+		op1 := c.popBig(NOTEQ)
+		op2 := c.popBig(NOTEQ)
+		c.push(boolean(op2.Cmp(op1) != 0))
+	case EQ:
+		op1 := c.popBig(EQ)
+		op2 := c.popBig(EQ)
+		c.push(boolean(op2.Cmp(op1) == 0))
+	case GT:
+		op1 := c.popBig(GT)
+		op2 := c.popBig(GT)
+		c.push(boolean(op2.Cmp(op1) > 0))
+	case GTOREQ:
+		op1 := c.popBig(GTOREQ)
+		op2 := c.popBig(GTOREQ)
+		c.push(boolean(op2.Cmp(op1) >= 0))
+
+	case ACOS:
+		c.pushBig(bigAcos(c.popBig(ACOS), work))
+	case ASIN:
+		c.pushBig(bigAsin(c.popBig(ASIN), work))
+	case ATAN:
+		c.pushBig(bigAtan(c.popBig(ATAN), work))
+	case COS:
+		c.pushBig(bigCos(c.popBig(COS), work))
+	case SIN:
+		c.pushBig(bigSin(c.popBig(SIN), work))
+	case COSH:
+		op1 := c.popBig(COSH)
+		ex := bigExp(op1, work)
+		sum := bigNew(work).Add(ex, bigNew(work).Quo(bigInt(1, work), ex))
+		c.pushBig(sum.SetMantExp(sum, -1))
+	case SINH:
+		op1 := c.popBig(SINH)
+		// e^x - e^-x cancels for small x, so carry as many extra bits as x is small
+		extra := work
+		if e := op1.MantExp(nil); e < 0 {
+			extra += uint(-e)
+		}
+		ex := bigExp(op1, extra)
+		diff := bigNew(extra).Sub(ex, bigNew(extra).Quo(bigInt(1, extra), ex))
+		c.pushBig(diff.SetMantExp(diff, -1))
+	case TANH:
+		op1 := c.popBig(TANH)
+		e2x := bigExp(bigNew(work).SetMantExp(op1, 1), work)
+		if e2x.IsInf() {
+			c.pushBig(bigInt(1, work))
+		} else {
+			one := bigInt(1, work)
+			c.pushBig(bigNew(work).Quo(bigNew(work).Sub(e2x, one), bigNew(work).Add(e2x, one)))
+		}
+
+	case CEIL:
+		op1 := c.popBig(CEIL)
+		result := bigTrunc(op1, work)
+		if op1.Sign() > 0 && result.Cmp(op1) != 0 {
+			result.Add(result, bigInt(1, work))
+		}
+		c.pushBig(result)
+	case FLOOR:
+		op1 := c.popBig(FLOOR)
+		result := bigTrunc(op1, work)
+		if op1.Sign() < 0 && result.Cmp(op1) != 0 {
+			result.Sub(result, bigInt(1, work))
+		}
+		c.pushBig(result)
+	case ROUND:
+		op1 := c.popBig(ROUND)
+		half := big.NewFloat(0.5)
+		if op1.Sign() < 0 {
+			half.Neg(half)
+		}
+		c.pushBig(bigTrunc(bigNew(work).Add(op1, half), work))
+	case IP:
+		c.pushBig(bigTrunc(c.popBig(IP), work))
+	case FP:
+		op1 := c.popBig(FP)
+		c.pushBig(bigNew(work).Sub(op1, bigTrunc(op1, work)))
+	case SIGN:
+		c.pushBig(bigInt(int64(c.popBig(SIGN).Sign()), work))
+	case ABS:
+		c.pushBig(bigNew(work).Abs(c.popBig(ABS)))
+	case MAX:
+		op1 := c.popBig(MAX)
+		op2 := c.popBig(MAX)
+		if op2.Cmp(op1) > 0 {
+			op1 = op2
+		}
+		c.pushBig(op1)
+	case MIN:
+		op1 := c.popBig(MIN)
+		op2 := c.popBig(MIN)
+		if op2.Cmp(op1) < 0 {
+			op1 = op2
+		}
+		c.pushBig(op1)
+
+	case EXP:
+		c.pushBig(bigExp(c.popBig(EXP), work))
+	case FACT:
+		op1 := c.popBig(FACT)
+		if n, _ := op1.Float64(); n > 1 {
+			c.checkBits(n * math.Log2(n))
+		}
+		c.pushBig(bigFactorial(op1, work))
+	case SQRT:
+		c.pushBig(bigNew(work).Sqrt(c.popBig(SQRT)))
+	case LN:
+		c.pushBig(bigLn(c.popBig(LN), work))
+	case LOG:
+		op1 := c.popBig(LOG)
+		c.pushBig(bigNew(work).Quo(bigLn(op1, work), bigLn(bigInt(10, work), work)))
+	case POW:
+		op1 := c.popBig(POW)
+		op2 := c.popBig(POW)
+		c.pushBig(bigPow(op2, op1, work))
+
+	case ASSERTNEAR:
+		tolerance := c.popBig(ASSERTNEAR)
+		expected := c.popBig(ASSERTNEAR)
+		actual := c.popBig(ASSERTNEAR)
+		if bigNew(work).Abs(bigNew(work).Sub(actual, expected)).Cmp(tolerance) > 0 {
+			throwAssertionError("expected %v ± %v but found %v", c.formatBig(expected), c.formatBig(tolerance), c.formatBig(actual))
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *Calculator) pushBig(x *big.Float) {
+	c.push(bigFloat{bigNew(precisionBits(c.precision)).Set(x)})
+}
+
+func (c *Calculator) popBig(command string) *big.Float {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	if x, ok := item.(float); ok && math.IsNaN(float64(x)) {
+		panic(big.ErrNaN{})
+	}
+	x, ok := bigValue(item, precisionBits(c.precision)+guardBits)
+	if !ok {
+		throwWrongElementType(Number, item.Kind())
+	}
+	return x
+}
+
+// bigValue -> the value of a number as a big.Float, rounding exact numbers to prec bits
+func bigValue(item Value, prec uint) (*big.Float, bool) {
+	switch x := item.(type) {
+	case float:
+		if !math.IsNaN(float64(x)) {
+			return new(big.Float).SetFloat64(float64(x)), true
+		}
+	case significant:
+		return new(big.Float).SetFloat64(x.value), true
+	case integer:
+		return new(big.Float).SetInt(x.value), true
+	case rational:
+		return bigNew(prec).SetRat(x.value), true
+	case decimal:
+		return bigNew(prec).SetRat(x.value), true
+	case bigFloat:
+		return x.value, true
+	}
+	return nil, false
+}
+
+func bigNew(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+func bigInt(n int64, prec uint) *big.Float {
+	return bigNew(prec).SetInt64(n)
+}
+
+// negligible -> whether adding term to sum would no longer change it at the given precision
+func negligible(term, sum *big.Float, prec uint) bool {
+	if term.Sign() == 0 {
+		return true
+	}
+	exp := term.MantExp(nil)
+	return exp < sum.MantExp(nil)-int(prec) || exp < -2*int(prec)
+}
+
+func bigTrunc(x *big.Float, prec uint) *big.Float {
+	if x.IsInf() || x.IsInt() {
+		return bigNew(prec).Set(x)
+	}
+	i, _ := x.Int(nil)
+	return bigNew(prec).SetInt(i)
+}
+
+func bigMod(x, y *big.Float, prec uint) *big.Float {
+	if y.Sign() == 0 || x.IsInf() {
+		panic(big.ErrNaN{})
+	}
+	if y.IsInf() {
+		return bigNew(prec).Set(x)
+	}
+	work := prec
+	if e := x.MantExp(nil) - y.MantExp(nil); e > 0 {
+		work += uint(e)
+	}
+	q := bigTrunc(bigNew(work).Quo(x, y), work)
+	return bigNew(prec).Sub(x, bigNew(work).Mul(q, y))
+}
+
+func bigFactorial(n *big.Float, prec uint) *big.Float {
+	if n.IsInt() && n.Sign() > 0 {
+		i, _ := n.Int64()
+		return bigNew(prec).SetInt(new(big.Int).MulRange(1, i))
+	}
+	result := bigInt(1, prec)
+	for x := bigNew(prec).Set(n); x.Sign() > 0; x.Sub(x, bigInt(1, prec)) {
+		result.Mul(result, x)
+	}
+	return result
+}
+
+func bigPow(x, y *big.Float, prec uint) *big.Float {
+	if y.IsInt() && y.MantExp(nil) < 32 {
+		n, _ := y.Int64()
+		negative := n < 0
+		if negative {
+			n = -n
+		}
+		work := prec + guardBits
+		result := bigInt(1, work)
+		square := bigNew(work).Set(x)
+		for ; n > 0; n >>= 1 {
+			if n&1 == 1 {
+				result.Mul(result, square)
+			}
+			square.Mul(square, square)
+		}
+		if negative {
+			result.Quo(bigInt(1, work), result)
+		}
+		return result
+	}
+	switch x.Sign() {
+	case -1:
+		panic(big.ErrNaN{})
+	case 0:
+		if y.Sign() < 0 {
+			return bigNew(prec).SetInf(false)
+		}
+		return bigInt(0, prec)
+	}
+	work := prec + guardBits
+	return bigExp(bigNew(work).Mul(y, bigLn(x, work)), prec)
+}
+
+// bigExp -> e^x, by squaring the sum of the series for x / 2^k
+func bigExp(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return bigInt(1, prec)
+	}
+	if x.IsInf() {
+		if x.Sign() > 0 {
+			return bigNew(prec).SetInf(false)
+		}
+		return bigInt(0, prec)
+	}
+	if x.Sign() < 0 {
+		return bigNew(prec).Quo(bigInt(1, prec), bigExp(bigNew(prec).Neg(x), prec+guardBits))
+	}
+
+	k := 0
+	if e := x.MantExp(nil); e > -10 {
+		k = e + 10
+	}
+	work := prec + uint(k) + guardBits
+	r := bigNew(work).SetMantExp(x, -k)
+	sum := bigInt(1, work)
+	term := bigInt(1, work)
+	for n := int64(1); ; n++ {
+		term.Mul(term, r)
+		term.Quo(term, bigInt(n, work))
+		if negligible(term, sum, work) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	for i := 0; i < k && !sum.IsInf(); i++ {
+		sum.Mul(sum, sum)
+	}
+	return bigNew(prec).Set(sum)
+}
+
+// bigLn -> the natural log of x, from ln(m * 2^e) = ln m + e ln 2
+func bigLn(x *big.Float, prec uint) *big.Float {
+	switch {
+	case x.Sign() < 0:
+		panic(big.ErrNaN{})
+	case x.Sign() == 0:
+		return bigNew(prec).SetInf(true)
+	case x.IsInf():
+		return bigNew(prec).SetInf(false)
+	}
+	work := prec + guardBits
+	mant := bigNew(work)
+	exp := x.MantExp(mant)
+	result := lnNewton(mant, work)
+	if exp != 0 {
+		ln2 := lnNewton(bigInt(2, work), work)
+		result.Add(result, ln2.Mul(ln2, bigInt(int64(exp), work)))
+	}
+	return bigNew(prec).Set(result)
+}
+
+// lnNewton -> the natural log of a number close to 1, refining the float64 result with Halley's method
+func lnNewton(x *big.Float, prec uint) *big.Float {
+	f, _ := x.Float64()
+	y := bigNew(prec).SetFloat64(math.Log(f))
+	for bits := uint(50); bits < 2*prec; bits *= 3 {
+		ey := bigExp(y, prec)
+		step := bigNew(prec).Sub(x, ey)
+		step.Quo(step, bigNew(prec).Add(x, ey))
+		y.Add(y, step.SetMantExp(step, 1))
+	}
+	return y
+}
+
+// bigPi -> π from Machin's formula, 16 atan(1/5) - 4 atan(1/239)
+func bigPi(prec uint) *big.Float {
+	work := prec + guardBits
+	a := atanInverse(5, work)
+	a.Mul(a, bigInt(16, work))
+	b := atanInverse(239, work)
+	b.Mul(b, bigInt(4, work))
+	return bigNew(prec).Sub(a, b)
+}
+
+func atanInverse(n int64, prec uint) *big.Float {
+	power := bigNew(prec).Quo(bigInt(1, prec), bigInt(n, prec))
+	square := bigInt(n*n, prec)
+	sum := bigNew(prec).Set(power)
+	for k := int64(1); ; k++ {
+		power.Quo(power, square)
+		term := bigNew(prec).Quo(power, bigInt(2*k+1, prec))
+		if negligible(term, sum, prec) {
+			break
+		}
+		if k%2 == 1 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+	}
+	return sum
+}
+
+func bigAtan(x *big.Float, prec uint) *big.Float {
+	work := prec + guardBits
+	if x.IsInf() {
+		halfPi := bigPi(work)
+		halfPi.SetMantExp(halfPi, -1)
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi
+	}
+
+	one := bigInt(1, work)
+	y := bigNew(work).Abs(x)
+	inverted := y.Cmp(one) > 0
+	if inverted {
+		y.Quo(one, y)
+	}
+	// atan(y) = 2 atan(y / (1 + sqrt(1 + y^2))), until the series converges quickly
+	doublings := 0
+	for y.Cmp(big.NewFloat(0.125)) > 0 {
+		t := bigNew(work).Mul(y, y)
+		t.Add(t, one)
+		t.Sqrt(t)
+		t.Add(t, one)
+		y.Quo(y, t)
+		doublings++
+	}
+
+	square := bigNew(work).Mul(y, y)
+	power := bigNew(work).Set(y)
+	sum := bigNew(work).Set(y)
+	for k := int64(1); ; k++ {
+		power.Mul(power, square)
+		term := bigNew(work).Quo(power, bigInt(2*k+1, work))
+		if negligible(term, sum, work) {
+			break
+		}
+		if k%2 == 1 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+	}
+	sum.SetMantExp(sum, doublings)
+
+	if inverted {
+		halfPi := bigPi(work)
+		sum.Sub(halfPi.SetMantExp(halfPi, -1), sum)
+	}
+	if x.Sign() < 0 {
+		sum.Neg(sum)
+	}
+	return bigNew(prec).Set(sum)
+}
+
+// bigAcos -> acos x = pi/2 - asin x, with both terms worked out at the same precision so that acos 1 is exactly 0
+func bigAcos(x *big.Float, prec uint) *big.Float {
+	work := prec + guardBits
+	switch x.Cmp(bigInt(1, work)) {
+	case 1:
+		panic(big.ErrNaN{})
+	case 0:
+		return bigNew(prec)
+	}
+	if x.Cmp(bigInt(-1, work)) == 0 {
+		return bigPi(prec)
+	}
+	halfPi := bigPi(work)
+	halfPi.SetMantExp(halfPi, -1)
+	return bigNew(prec).Sub(halfPi, bigAsin(x, work))
+}
+
+func bigAsin(x *big.Float, prec uint) *big.Float {
+	work := prec + guardBits
+	one := bigInt(1, work)
+	switch bigNew(work).Abs(x).Cmp(one) {
+	case 1:
+		panic(big.ErrNaN{})
+	case 0:
+		halfPi := bigPi(work)
+		halfPi.SetMantExp(halfPi, -1)
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi
+	}
+	// asin x = atan(x / sqrt(1 - x^2))
+	t := bigNew(work).Mul(x, x)
+	t.Sub(one, t)
+	t.Sqrt(t)
+	return bigAtan(t.Quo(x, t), prec)
+}
+
+// reduceAngle -> x modulo 2π, in [-π, π]
+func reduceAngle(x *big.Float, prec uint) *big.Float {
+	if x.IsInf() {
+		panic(big.ErrNaN{})
+	}
+	work := prec + guardBits
+	if e := x.MantExp(nil); e > 0 {
+		work += uint(e)
+	}
+	twoPi := bigPi(work)
+	twoPi.SetMantExp(twoPi, 1)
+	turns := bigNew(work).Quo(x, twoPi)
+	half := big.NewFloat(0.5)
+	if turns.Sign() < 0 {
+		half.Neg(half)
+	}
+	turns = bigTrunc(turns.Add(turns, half), work)
+	return bigNew(prec).Sub(x, turns.Mul(turns, twoPi))
+}
+
+func bigSin(x *big.Float, prec uint) *big.Float {
+	work := prec + guardBits
+	r := reduceAngle(x, work)
+	square := bigNew(work).Mul(r, r)
+	term := bigNew(work).Set(r)
+	sum := bigNew(work).Set(r)
+	for k := int64(1); ; k++ {
+		term.Mul(term, square)
+		term.Quo(term, bigInt(-(2*k)*(2*k+1), work))
+		if negligible(term, sum, work) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return bigNew(prec).Set(sum)
+}
+
+func bigCos(x *big.Float, prec uint) *big.Float {
+	work := prec + guardBits
+	r := reduceAngle(x, work)
+	square := bigNew(work).Mul(r, r)
+	term := bigInt(1, work)
+	sum := bigInt(1, work)
+	for k := int64(1); ; k++ {
+		term.Mul(term, square)
+		term.Quo(term, bigInt(-(2*k-1)*(2*k), work))
+		if negligible(term, sum, work) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return bigNew(prec).Set(sum)
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// resultTest -> a line run in a fresh calculator, and the items it leaves on the stack as they are shown or the error
+// it fails with
+type resultTest struct {
+	line     string
+	expected string
+}
+
+func testResults(t *testing.T, tests []resultTest) {
+	t.Helper()
+	for _, test := range tests {
+		c := New()
+		var got string
+		if err := c.Eval(test.line); err != nil {
+			got = "error: " + err.Error()
+		} else {
+			shown := make([]string, len(c.Stack()))
+			for i, item := range c.Stack() {
+				shown[i] = c.Show(item)
+			}
+			got = strings.Join(shown, ", ")
+		}
+		if got != test.expected {
+			t.Errorf("%q: got %v, expected %v", test.line, got, test.expected)
+		}
+	}
+}
+
+func TestBigFloat(t *testing.T) {
+	testResults(t, []resultTest{
+		{"50 prec 2 sqrt", "1.414213562373095048801688724209698078569671875377"},
+		{"50 prec 2 sqrt dup *", "2"},
+		{"20 prec 2 0.5 pow", "1.4142135623730950488"},
+		{"0 prec 2 sqrt", "1.4142135623730951"},
+		{"30 prec 0.1 0.2 +", "0.3"},
+		{"30 prec 1.0 3 /", "0.333333333333333333333333333333"},
+		{"40 prec 10 sqrt 3 >", "true"},
+		{"30 prec 1 exp", "2.71828182845904523536028747135"},
+		{"30 prec 2 ln", "0.693147180559945309417232121458"},
+		{"30 prec 100 log", "2"},
+		{"30 prec pi", "3.14159265358979323846264338328"},
+		{"30 prec 1 atan 4 *", "3.14159265358979323846264338328"},
+		{"30 prec 0.5 sin", "0.479425538604203000273287935216"},
+		{"30 prec 0.5 cos", "0.877582561890372716116281582604"},
+		{"50 prec 0.5 acos", "1.047197551196597746154214461093167628065723133125"},
+		{"50 prec 1 acos", "0"},
+		{"50 prec -1 acos", "3.1415926535897932384626433832795028841971693993751"},
+		{"30 prec -2.5 sign", "-1"},
+	})
+}
@@ -1,37 +1,136 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
-	"os"
 	"strings"
-	"time"
 )
 
-var stack []Token
-var values = make(map[string]Token)
-var macros = make(map[string][]string)
+// Calculator -> a stack with its registers, macros and settings. Each calculator is independent of the others,
+// but a single calculator must not be used from more than one goroutine at a time.
+type Calculator struct {
+	stack  []Value
+	values map[string]Value
+	macros map[string][]string
+	// shared -> whether the registers and macros are shared with the calculators of a pool, and copied before they
+	// change
+	shared bool
+	// pooled -> whether the calculator evaluates the lines of a pool, which run at once and can't print anything
+	pooled bool
+	// compiled -> the code for each macro, compiled for the mode and precision it was first called in
+	compiled map[compiledKey][]instruction
+	// unoptimized -> whether macros are compiled exactly as they are written, see DisableOptimizations
+	unoptimized bool
 
-func eval(commands []string) {
+	mode    string
+	display string
+	// mixed -> whether fractions are shown as mixed numbers, 1 1/2 instead of 3/2
+	mixed bool
+	// precision -> the number of significant digits numbers are kept to, or 0 to use float64
+	precision int
+	// scale -> the number of decimal places decimals are rounded to after multiplying and dividing, and shown with
+	scale int
+	// rounding -> how decimals are rounded: half-even, half-up, down or ceiling
+	rounding string
+	// promote -> whether real operations that would give NaN give a complex number instead
+	promote bool
+	// sources -> the number of independent uncertainties created so far, used to tell them apart
+	sources int
+
+	// operators -> the commands of this calculator by name, which are the built-in ones until some are registered
+	// or disabled. The tables are shared with the built-in ones until then, and copied before they change.
+	operators map[string]*operator
+	aliases   map[string]string
+	owned     bool
+	// custom -> the registered commands, in the order they were registered
+	custom []*operator
+
+	// limits -> the limits of the calculation run by EvalContext, which is stopped when ctx is done
+	limits *Limits
+	ctx    context.Context
+	steps  int
+	// calls -> the number of macro calls in progress
+	calls int
+
+	// rng -> the source of random numbers, which rand makes when it is first run unless seed makes it first
+	rng        *rand.Rand
+	seeded     bool
+	randomSeed int64
+	// sandboxed -> whether commands that act outside the calculator are taken away, see Sandbox
+	sandboxed bool
+	// imports -> the directories a sandboxed calculator can import scripts from
+	imports []string
+	// importing -> the scripts being imported, to stop a script that imports itself
+	importing map[string]bool
+	// plugins -> the commands registered by plugins
+	plugins []string
+}
+
+// New -> a calculator with an empty stack and the built-in commands
+func New() *Calculator {
+	c := &Calculator{operators: operators, aliases: aliases}
+	c.reset()
+	return c
+}
+
+// Eval -> run a line of commands, leaving the stack, registers, macros and settings as they were if one of them
+// fails
+func (c *Calculator) Eval(line string) error {
+	// the registers and macros are copied before the line changes them, as they are for the calculators of a pool
+	saved, shared := *c, c.shared
+	saved.stack = append([]Value(nil), c.stack...)
+	c.shared = true
+	err := protect(func() { c.eval(tokenize(line)) })
+	if err != nil && err != ErrExit {
+		*c = saved
+	} else if c.shared {
+		c.shared = shared
+	}
+	return err
+}
+
+// Stack -> the items on the stack, bottom first
+func (c *Calculator) Stack() []Value {
+	return append([]Value(nil), c.stack...)
+}
+
+// Show -> an item as it is shown on the stack of this calculator, in its mode and with its settings
+func (c *Calculator) Show(item Value) string {
+	return item.show(c)
+}
+
+func (c *Calculator) eval(commands []string) {
 	for i, item := range commands {
 		if item == "" {
 			continue
 		}
-		token, err := ParseToken(strings.TrimSpace(item))
+		c.step()
+		token, err := c.ParseToken(strings.TrimSpace(item))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "rpn: %v\n", err)
-			exit()
+			throw("%v", err)
 		}
 
-		if token.Type == MACRODEF || token.Type == REPEAT {
+		if token.Type == MACRODEF || token.Type == REPEAT || token.Type == DISASM {
 			switch token.Type {
 			case REPEAT:
-				n := popNumber(token.Type)
+				n := c.popNumber(token.Type)
 				if len(commands[i:]) < 2 {
 					throwNotEnoughArgumentsError(REPEAT)
 				}
 				command := commands[i+1]
+				if !isSpecialForm(command) {
+					// run the command n times rather than writing it out n times, which a large n would run out of
+					// memory for
+					for j := 0; j < int(n); j++ {
+						c.eval(commands[i+1 : i+2])
+					}
+					c.eval(commands[i+2:])
+					break
+				}
 				var newCommands []string
 				for i := 0; i < int(n); i++ {
 					newCommands = append(newCommands, command)
@@ -39,350 +138,377 @@ func eval(commands []string) {
 				if len(commands[i:]) > 2 {
 					newCommands = append(newCommands, commands[i+2:]...)
 				}
-				eval(newCommands)
+				c.eval(newCommands)
 			case MACRODEF:
 				if len(commands[i:]) < 3 {
 					throwNotEnoughArgumentsError(MACRODEF)
 				}
-				macros[commands[i+1]] = commands[i+2:]
+				c.define(commands[i+1], commands[i+2:])
+			case DISASM:
+				if len(commands[i:]) < 2 {
+					throwNotEnoughArgumentsError(DISASM)
+				}
+				c.disassemble(commands[i+1])
+				c.eval(commands[i+2:])
 			}
 			break
 		} else {
-			handleCommand(token)
+			c.handleCommand(token)
 		}
 	}
 }
 
-func handleCommand(token Token) {
-	switch token.Type {
-	case NUMBER:
-		push(token)
-	case RAND:
-		rand.Seed(time.Now().UnixNano())
-		push(Token{Type: NUMBER, Literal: rand.Float64()})
-	case PLUS:
-		op1 := popNumber(PLUS)
-		op2 := popNumber(PLUS)
-		push(Token{Type: NUMBER, Literal: op2 + op1})
-	case MINUS:
-		op1 := popNumber(MINUS)
-		op2 := popNumber(MINUS)
-		push(Token{Type: NUMBER, Literal: op2 - op1})
-	case MULTIPLY:
-		op1 := popNumber(MULTIPLY)
-		op2 := popNumber(MULTIPLY)
-		push(Token{Type: NUMBER, Literal: op2 * op1})
-	case DIVIDE:
-		op1 := popNumber(DIVIDE)
-		op2 := popNumber(DIVIDE)
-		push(Token{Type: NUMBER, Literal: op2 / op1})
-	case CLRSTACK:
-		stack = make([]Token, 0)
-	case CLRVARS:
-		values = make(map[string]Token)
-	case CLRALL:
-		stack = make([]Token, 0)
-		values = make(map[string]Token)
-	case NOT:
-		op1 := popBoolean(NOT)
-		push(Token{Type: BOOLEAN, Literal: !op1})
-	case MOD:
-		op1 := popNumber(MOD)
-		op2 := popNumber(MOD)
-		push(Token{Type: NUMBER, Literal: math.Mod(op2, op1)})
-	case DECR:
-		op1 := popNumber(DECR)
-		push(Token{Type: NUMBER, Literal: op1 - 1})
-	case INCR:
-		op1 := popNumber(INCR)
-		push(Token{Type: NUMBER, Literal: op1 + 1})
-
-	case BITAND:
-		op1 := popNumber(BITAND)
-		op2 := popNumber(BITAND)
-		push(Token{Type: NUMBER, Literal: float64(int64(op2) & int64(op1))})
-	case BITOR:
-		op1 := popNumber(BITOR)
-		op2 := popNumber(BITOR)
-		push(Token{Type: NUMBER, Literal: float64(int64(op2) | int64(op1))})
-	case BITXOR:
-		op1 := popNumber(BITXOR)
-		op2 := popNumber(BITXOR)
-		push(Token{Type: NUMBER, Literal: float64(int64(op2) ^ int64(op1))})
-	case BITNOT:
-		op1 := popNumber(BITNOT)
-		op2 := popNumber(BITNOT)
-		push(Token{Type: NUMBER, Literal: float64(int64(op2) &^ int64(op1))})
-	case BITLEFT:
-		op1 := popNumber(BITLEFT)
-		op2 := popNumber(BITLEFT)
-		push(Token{Type: NUMBER, Literal: float64(int64(op2) << int64(op1))})
-	case BITRIGHT:
-		op1 := popNumber(BITRIGHT)
-		op2 := popNumber(BITRIGHT)
-		push(Token{Type: NUMBER, Literal: float64(int64(op2) >> int64(op1))})
-
-	case BOOLAND:
-		op1 := popBoolean(BOOLAND)
-		op2 := popBoolean(BOOLAND)
-		push(Token{Type: BOOLEAN, Literal: op2 && op1})
-	case BOOLOR:
-		op1 := popBoolean(BOOLAND)
-		op2 := popBoolean(BOOLAND)
-		push(Token{Type: BOOLEAN, Literal: op2 || op1})
-	case BOOLXOR:
-		op1 := popBoolean(BOOLAND)
-		op2 := popBoolean(BOOLAND)
-		push(Token{Type: BOOLEAN, Literal: op2 != op1})
-
-	case LT:
-		op1 := popNumber(LT)
-		op2 := popNumber(LT)
-		push(Token{Type: BOOLEAN, Literal: op2 < op1})
-	case LTOREQ:
-		op1 := popNumber(LT)
-		op2 := popNumber(LT)
-		push(Token{Type: BOOLEAN, Literal: op2 <= op1})
-	case NOTEQ:
-		op1 := popNumber(LT)
-		op2 := popNumber(LT)
-		push(Token{Type: BOOLEAN, Literal: op2 != op1})
-	case EQ:
-		op1 := popNumber(LT)
-		op2 := popNumber(LT)
-		push(Token{Type: BOOLEAN, Literal: op2 == op1})
-	case GT:
-		op1 := popNumber(LT)
-		op2 := popNumber(LT)
-		push(Token{Type: BOOLEAN, Literal: op2 > op1})
-	case GTOREQ:
-		op1 := popNumber(LT)
-		op2 := popNumber(LT)
-		push(Token{Type: BOOLEAN, Literal: op2 >= op1})
-
-	case ACOS:
-		op1 := popNumber(ACOS)
-		push(Token{Type: NUMBER, Literal: math.Acos(op1)})
-	case ASIN:
-		op1 := popNumber(ASIN)
-		push(Token{Type: NUMBER, Literal: math.Asin(op1)})
-	case ATAN:
-		op1 := popNumber(ATAN)
-		push(Token{Type: NUMBER, Literal: math.Atan(op1)})
-	case COS:
-		op1 := popNumber(COS)
-		push(Token{Type: NUMBER, Literal: math.Cos(op1)})
-	case COSH:
-		op1 := popNumber(COSH)
-		push(Token{Type: NUMBER, Literal: math.Cosh(op1)})
-	case SIN:
-		op1 := popNumber(SIN)
-		push(Token{Type: NUMBER, Literal: math.Sin(op1)})
-	case SINH:
-		op1 := popNumber(SINH)
-		push(Token{Type: NUMBER, Literal: math.Sinh(op1)})
-	case TANH:
-		op1 := popNumber(TANH)
-		push(Token{Type: NUMBER, Literal: math.Tanh(op1)})
-
-	case CEIL:
-		op1 := popNumber(CEIL)
-		push(Token{Type: NUMBER, Literal: math.Ceil(op1)})
-	case FLOOR:
-		op1 := popNumber(FLOOR)
-		push(Token{Type: NUMBER, Literal: math.Floor(op1)})
-	case ROUND:
-		op1 := popNumber(ROUND)
-		push(Token{Type: NUMBER, Literal: math.Round(op1)})
-	case IP:
-		op1 := popNumber(IP)
-		push(Token{Type: NUMBER, Literal: float64(int(op1))})
-	case FP:
-		op1 := popNumber(IP)
-		value := op1 - float64(int(op1))
-		push(Token{Type: NUMBER, Literal: value})
-	case SIGN:
-		op1 := popNumber(IP)
-		if op1 >= 0 {
-			push(Token{Type: NUMBER, Literal: 0})
-		} else {
-			push(Token{Type: NUMBER, Literal: -1})
-		}
-	case ABS:
-		op1 := popNumber(ABS)
-		push(Token{Type: NUMBER, Literal: math.Abs(op1)})
-	case MAX:
-		op1 := popNumber(POW)
-		op2 := popNumber(POW)
-		push(Token{Type: NUMBER, Literal: math.Max(op2, op1)})
-	case MIN:
-		op1 := popNumber(POW)
-		op2 := popNumber(POW)
-		push(Token{Type: NUMBER, Literal: math.Min(op2, op1)})
-
-	case HEX:
-		mode = HEX
-	case DEC:
-		mode = DEC
-	case BIN:
-		mode = BIN
-	case OCT:
-		mode = OCT
-
-	case EXP:
-		op1 := popNumber(EXP)
-		push(Token{Type: NUMBER, Literal: math.Exp(op1)})
-	case FACT:
-		op1 := popNumber(FACT)
-		push(Token{Type: NUMBER, Literal: factorial(op1)})
-	case SQRT:
-		op1 := popNumber(SQRT)
-		push(Token{Type: NUMBER, Literal: math.Sqrt(op1)})
-	case LN:
-		op1 := popNumber(LN)
-		push(Token{Type: NUMBER, Literal: math.Log(op1)})
-	case LOG:
-		op1 := popNumber(LOG)
-		push(Token{Type: NUMBER, Literal: math.Log10(op1)})
-	case POW:
-		op1 := popNumber(POW)
-		op2 := popNumber(POW)
-		push(Token{Type: NUMBER, Literal: math.Pow(op2, op1)})
-
-	case PICK:
-		op1 := popNumber(PICK)
-		if len(stack) <= int(op1) {
-			throwNotEnoughElementsError(PICK)
-		} else {
-			stack = remove(stack, int(op1))
-		}
-	case DEPTH:
-		push(Token{Type: NUMBER, Literal: float64(len(stack))})
-	case DROP:
-		if len(stack) < 1 {
-			throwNotEnoughElementsError(DUP)
-		}
-		pop()
-	case DROPN:
-		n := popNumber(DROPN)
-		if len(stack) < int(n) {
-			throwNotEnoughElementsError(DROPN)
-		} else {
-			for i := 0; i <= int(n); i++ {
-				pop()
-			}
-		}
-	case DUP:
-		if len(stack) < 1 {
-			throwNotEnoughElementsError(DUP)
-		} else {
-			op1, _ := pop()
-			push(op1)
-			push(op1)
-		}
-	case DUPN:
-		n := popNumber(DUPN)
-		if len(stack) < int(n) {
-			throwNotEnoughElementsError(DROPN)
-			return
-		}
-		temp := make([]Token, 0)
-		for i := 0; i < int(n); i++ {
-			item, _ := pop()
-			temp = append(temp, item)
-		}
-		for i := int(n) - 1; i >= 0; i-- {
-			push(temp[i])
+// handler -> runs commands whose operands have particular types, returning false for the commands it doesn't cover
+type handler struct {
+	// operands -> the types all the operands must have, or none for a handler that checks its operands itself
+	operands kindSet
+	// requires -> a type that at least one of the operands must have, or Any
+	requires Kind
+	handle   func(*Calculator, Token) bool
+}
 
-			push(temp[i])
-		}
-	case ROLL:
-		if len(stack) > 1 {
-			stackEnd := len(stack) - 1
-			stack = append(stack[stackEnd:], stack[:stackEnd]...)
+// handlers -> the handlers tried in turn before running a command on float64s, the most specific types first
+var handlers []handler
+
+func init() {
+	handlers = []handler{
+		{handle: (*Calculator).handleString},
+		{handle: (*Calculator).handleList},
+		{handle: (*Calculator).handlePolynomial},
+		{handle: (*Calculator).handleMatrix},
+		{handle: (*Calculator).handleVector},
+		{handle: (*Calculator).handleComplexParts},
+		{operands: realsAnd(Complex), requires: Complex, handle: (*Calculator).handleComplex},
+		{operands: setOf(reals...), handle: (*Calculator).promoteComplex},
+		{operands: realsAnd(Interval), requires: Interval, handle: (*Calculator).handleInterval},
+		{handle: (*Calculator).handlePlusMinus},
+		{operands: realsAnd(Measurement), requires: Measurement, handle: (*Calculator).handleMeasurement},
+		{handle: (*Calculator).handleSigfigs},
+		{operands: realsAnd(Significant), requires: Significant, handle: (*Calculator).handleSignificant},
+		{operands: setOf(Decimal, Integer, Rational), requires: Decimal, handle: (*Calculator).handleDecimal},
+		{operands: setOf(Integer), handle: (*Calculator).handleInteger},
+		{operands: setOf(Integer, Rational), handle: (*Calculator).handleRational},
+		{handle: (*Calculator).handleBig},
+	}
+}
+
+// command -> a built-in command resolved to its operator and the number of operands it takes, which compiled code
+// does once rather than each time the command runs
+type command struct {
+	token    Token
+	operator *operator
+	arity    int
+}
+
+func (c *Calculator) resolve(token Token) command {
+	return command{token: token, operator: c.operators[token.Type], arity: len(effects[token.Type].in)}
+}
+
+// dispatch -> run a command with the first handler that takes its operands
+func (c *Calculator) dispatch(token Token, arity int) bool {
+	// the types of the operands, or none when the command takes none or the stack doesn't have them all
+	var operands kindSet
+	if arity > 0 && len(c.stack) >= arity {
+		for _, item := range c.stack[len(c.stack)-arity:] {
+			operands |= setOf(item.Kind())
 		}
-	case ROLLD:
-		if len(stack) > 1 {
-			stack = append(stack[1:], stack[0])
+	}
+	for _, h := range handlers {
+		if h.operands != 0 && (operands == 0 || operands&^h.operands != 0) {
+			continue
 		}
-	case STACK:
-		if display == "horizontal" {
-			display = "vertical"
-		} else {
-			display = "horizontal"
+		if h.requires != Any && !operands.has(h.requires) {
+			continue
 		}
-	case SWAP:
-		if len(stack) < 2 {
-			throwNotEnoughElementsError(SWAP)
-			return
+		if h.handle(c, token) {
+			return true
 		}
-		op1, _ := pop()
-		op2, _ := pop()
-		push(op1)
-		push(op2)
+	}
+	return false
+}
+
+func (c *Calculator) handleCommand(token Token) {
+	switch token.Type {
+	case VALUE:
+		c.push(token.Value)
+		return
 	case MACRO:
-		eval(macros[token.Literal.(string)])
-	case ASSIGN:
-		if len(stack) < 1 {
-			throwNotEnoughElementsError(ASSIGN)
-			return
+		c.runMacro(token.Argument)
+		return
+	}
+	c.runCommand(c.resolve(token))
+}
+
+func (c *Calculator) runCommand(command command) {
+	op := command.operator
+	if op != nil && op.custom {
+		op.run(c, command.token)
+		return
+	}
+	if c.dispatch(command.token, command.arity) {
+		return
+	}
+	if op != nil && op.run != nil {
+		op.run(c, command.token)
+	}
+}
+
+func (c *Calculator) toFrac(Token) {
+	op1 := c.popNumber(TOFRAC)
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(TOFRAC)
+	}
+	x, ok := ratValue(item)
+	if !ok {
+		throwWrongElementType(Number, item.Kind())
+	}
+	if op1 < 1 {
+		throw("The largest denominator must be at least 1: %v", TOFRAC)
+	}
+	max, _ := big.NewFloat(math.Floor(op1)).Int(nil)
+	c.pushRational(toFraction(x, max))
+}
+
+func (c *Calculator) clearStack(Token) {
+	c.stack = make([]Value, 0)
+}
+
+func (c *Calculator) clearValues(Token) {
+	c.unshare()
+	c.values = make(map[string]Value)
+}
+
+func (c *Calculator) clearAll(Token) {
+	c.unshare()
+	c.stack = make([]Value, 0)
+	c.values = make(map[string]Value)
+}
+
+func (c *Calculator) pick(Token) {
+	op1 := c.popNumber(PICK)
+	if !(op1 >= 0) || float64(len(c.stack)) <= op1 {
+		throwNotEnoughElementsError(PICK)
+	}
+	c.stack = remove(c.stack, int(op1))
+}
+
+func (c *Calculator) depth(Token) {
+	c.pushInteger(big.NewInt(int64(len(c.stack))))
+}
+
+func (c *Calculator) drop(Token) {
+	if len(c.stack) < 1 {
+		throwNotEnoughElementsError(DROP)
+	}
+	c.pop()
+}
+
+func (c *Calculator) dropN(Token) {
+	n := c.popNumber(DROPN)
+	if len(c.stack) < int(n) {
+		throwNotEnoughElementsError(DROPN)
+	} else {
+		for i := 0; i < int(n); i++ {
+			c.pop()
 		}
-		variable, _ := pop()
+	}
+}
 
-		values[token.Literal.(string)] = variable
+func (c *Calculator) dup(Token) {
+	if len(c.stack) < 1 {
+		throwNotEnoughElementsError(DUP)
+	} else {
+		op1, _ := c.pop()
+		c.push(op1)
+		c.push(op1)
+	}
+}
 
-	case EXIT:
-		fmt.Println("Goodbye")
-		os.Exit(0)
+func (c *Calculator) dupN(Token) {
+	n := c.popNumber(DUPN)
+	if len(c.stack) < int(n) {
+		throwNotEnoughElementsError(DUPN)
+		return
+	}
+	temp := make([]Value, 0)
+	for i := 0; i < int(n); i++ {
+		item, _ := c.pop()
+		temp = append(temp, item)
+	}
+	for i := int(n) - 1; i >= 0; i-- {
+		c.push(temp[i])
+
+		c.push(temp[i])
 	}
 }
 
-func push(element Token) {
-	stack = append(stack, element)
+func (c *Calculator) roll(Token) {
+	if len(c.stack) > 1 {
+		stackEnd := len(c.stack) - 1
+		c.stack = append(c.stack[stackEnd:], c.stack[:stackEnd]...)
+	}
+}
+
+func (c *Calculator) rollDown(Token) {
+	if len(c.stack) > 1 {
+		c.stack = append(c.stack[1:], c.stack[0])
+	}
 }
 
-func popNumber(command string) float64 {
-	item, err := pop()
+func (c *Calculator) swap(Token) {
+	if len(c.stack) < 2 {
+		throwNotEnoughElementsError(SWAP)
+		return
+	}
+	op1, _ := c.pop()
+	op2, _ := c.pop()
+	c.push(op1)
+	c.push(op2)
+}
+
+func (c *Calculator) assign(token Token) {
+	if len(c.stack) < 1 {
+		throwNotEnoughElementsError(ASSIGN)
+		return
+	}
+	variable, _ := c.pop()
+
+	name := token.Argument
+	c.unshare()
+	if _, ok := c.values[name]; !ok {
+		// a new register hides any macro of the same name that was inlined
+		c.compiled = make(map[compiledKey][]instruction)
+	}
+	c.values[name] = variable
+}
+
+func (c *Calculator) assert(Token) {
+	if !c.popBoolean(ASSERT) {
+		throwAssertionError("expected true but found false")
+	}
+}
+
+func (c *Calculator) assertEqual(Token) {
+	if len(c.stack) < 2 {
+		throwNotEnoughElementsError(ASSERTEQ)
+	}
+	expected, _ := c.pop()
+	actual, _ := c.pop()
+	if !expected.Equal(actual) {
+		throwAssertionError("expected %v but found %v", expected.show(c), actual.show(c))
+	}
+}
+
+func (c *Calculator) assertNear(Token) {
+	tolerance := c.popNumber(ASSERTNEAR)
+	expected := c.popNumber(ASSERTNEAR)
+	actual := c.popNumber(ASSERTNEAR)
+	if math.Abs(actual-expected) > tolerance {
+		throwAssertionError("expected %v ± %v but found %v", float(expected).show(c), float(tolerance).show(c), float(actual).show(c))
+	}
+}
+
+// ErrExit -> the error a calculation stops with when it runs exit, which Calculate and Repl take as the end of the
+// session
+var ErrExit = errors.New("exit")
+
+func (c *Calculator) exit(Token) {
+	panic(calcError{ErrExit})
+}
+
+// reset -> return the calculator to its initial state
+func (c *Calculator) reset() {
+	c.stack = make([]Value, 0)
+	c.values = make(map[string]Value)
+	c.macros = make(map[string][]string)
+	c.compiled = make(map[compiledKey][]instruction)
+	c.mode = DEC
+	c.precision = 0
+	c.scale = 2
+	c.rounding = "half-even"
+	c.display = "horizontal"
+	c.mixed = false
+	c.promote = false
+}
+
+func (c *Calculator) push(element Value) {
+	c.checkDepth(len(c.stack) + 1)
+	if c.limits != nil && c.limits.Size > 0 {
+		c.checkSize(float64(sizeOf(element, c.limits.Size)))
+	}
+	c.stack = append(c.stack, element)
+}
+
+func (c *Calculator) popNumber(command string) float64 {
+	item, err := c.pop()
 	if err != nil {
 		throwNotEnoughElementsError(command)
 	}
-	if item.Type != NUMBER {
-		throwWrongElementType(NUMBER, item.Type)
+	number, ok := numberValue(item)
+	if !ok {
+		throwWrongElementType(Number, item.Kind())
 	}
 
-	return item.Literal.(float64)
+	return number
+}
+
+// numberValue -> the value of any kind of number as a float64
+func numberValue(item Value) (float64, bool) {
+	switch x := item.(type) {
+	case float:
+		return float64(x), true
+	case significant:
+		return x.value, true
+	case integer:
+		return integerToFloat(x.value), true
+	case rational:
+		number, _ := x.value.Float64()
+		return number, true
+	case decimal:
+		number, _ := x.value.Float64()
+		return number, true
+	case bigFloat:
+		number, _ := x.value.Float64()
+		return number, true
+	}
+	return 0, false
 }
 
-func popBoolean(command string) bool {
-	item, err := pop()
+func (c *Calculator) popBoolean(command string) bool {
+	item, err := c.pop()
 	if err != nil {
 		throwNotEnoughElementsError(command)
 	}
-	if item.Type != BOOLEAN {
-		throwWrongElementType(BOOLEAN, item.Type)
+	b, ok := item.(boolean)
+	if !ok {
+		throwWrongElementType(Boolean, item.Kind())
 	}
 
-	return item.Literal.(bool)
+	return bool(b)
 }
 
-func pop() (Token, error) {
-	length := len(stack)
+func (c *Calculator) pop() (Value, error) {
+	length := len(c.stack)
 	if length == 0 {
-		return Token{}, fmt.Errorf("Popping from an empty stack")
+		return nil, fmt.Errorf("Popping from an empty stack")
 	}
-	var element Token
-	stack, element = stack[:length-1], stack[length-1]
+	var element Value
+	c.stack, element = c.stack[:length-1], c.stack[length-1]
 
 	return element, nil
 }
 
+// factorial -> n(n-1)(n-2)... down to the last positive factor. The product overflows after at most 171 factors,
+// so large numbers take no longer than small ones.
 func factorial(n float64) float64 {
-	if n > 0 {
-		return n * factorial(n-1)
+	result := 1.0
+	for ; n > 0 && !math.IsInf(result, 0); n-- {
+		result *= n
 	}
-	return 1
+	return result
 }
 
-func remove(slice []Token, s int) []Token {
+func remove(slice []Value, s int) []Value {
 	return append(slice[:s], slice[s+1:]...)
 }
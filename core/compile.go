@@ -0,0 +1,143 @@
+package core
+
+import "strings"
+
+type opcode int
+
+const (
+	opPush    opcode = iota // push a constant
+	opCommand               // run a built-in command
+	opWord                  // push a number or register, or call a macro, resolved when it runs
+	opRepeat                // pop n and run the body n times
+	opDefine                // define a macro
+	opEval                  // interpret the words, for the rare forms the compiler leaves alone
+	opCheck                 // fail unless the stack has enough items for a command that was optimised away
+)
+
+// instruction -> a single step of a compiled macro
+type instruction struct {
+	op    opcode
+	value Value
+	// command -> what an opCommand runs, resolved while compiling
+	command
+	name string
+	// numbers holds what an opWord parses to in each input mode, for when the mode isn't known while compiling.
+	// At a precision other than float64 the word is parsed again when it runs.
+	numbers [len(modes)]Value
+	parsed  [len(modes)]bool
+	body    []instruction
+	words   []string
+	// depth -> the number of items an opCheck needs
+	depth int
+}
+
+// modes -> the input modes numbers can be parsed in
+var modes = [...]string{DEC, HEX, OCT, BIN, DECIMAL, SIGFIG}
+
+func modeIndex(mode string) int {
+	for i, m := range modes {
+		if m == mode {
+			return i
+		}
+	}
+	return 0
+}
+
+type compiledKey struct {
+	name      string
+	mode      string
+	precision int
+}
+
+// compiler -> tracks the input mode and precision while compiling, so that literals can be parsed once.
+// The mode is empty and the precision is -1 when they can't be known until the code runs, e.g. after a macro call.
+type compiler struct {
+	calc      *Calculator
+	mode      string
+	precision int
+	inlining  map[string]bool
+}
+
+// compile -> turn a sequence of commands into instructions, resolving everything that can't change before they run
+func (c *Calculator) compile(words []string, mode string, digits int) []instruction {
+	compiler := compiler{calc: c, mode: mode, precision: digits, inlining: make(map[string]bool)}
+	return compiler.compile(words)
+}
+
+func (c *compiler) compile(words []string) []instruction {
+	var code []instruction
+	for i := 0; i < len(words); i++ {
+		word := strings.TrimSpace(words[i])
+		if word == "" {
+			continue
+		}
+		token, ok := c.calc.keyword(word)
+		if !ok {
+			code = c.word(code, word)
+			continue
+		}
+
+		switch token.Type {
+		case REPEAT:
+			if i+1 >= len(words) || isSpecialForm(words[i+1]) {
+				return append(code, instruction{op: opEval, words: words[i:]})
+			}
+			mode, digits := c.mode, c.precision
+			body := c.compile(words[i+1 : i+2])
+			if c.mode != mode {
+				c.mode = ""
+			}
+			if c.precision != digits {
+				c.precision = -1
+			}
+			code = append(code, instruction{op: opRepeat, body: body})
+			i++
+		case MACRODEF:
+			if len(words)-i < 3 {
+				return append(code, instruction{op: opEval, words: words[i:]})
+			}
+			return append(code, instruction{op: opDefine, name: words[i+1], words: words[i+2:]})
+		case DISASM:
+			return append(code, instruction{op: opEval, words: words[i:]})
+		case HEX, DEC, BIN, OCT, DECIMAL, SIGFIG:
+			c.mode = token.Type
+			code = append(code, instruction{op: opCommand, command: c.calc.resolve(token), name: word})
+		case PREC:
+			c.precision = -1
+			code = append(code, instruction{op: opCommand, command: c.calc.resolve(token), name: word})
+		default:
+			code = c.emit(code, instruction{op: opCommand, command: c.calc.resolve(token), name: word})
+		}
+	}
+	return code
+}
+
+// word -> compile a word that isn't a built-in command
+func (c *compiler) word(code []instruction, word string) []instruction {
+	if c.mode != "" && c.precision >= 0 {
+		if number, err := parseValue(word, c.mode, c.precision); err == nil {
+			return append(code, instruction{op: opPush, value: number})
+		}
+	}
+	if body, ok := c.inline(word); ok {
+		for _, in := range body {
+			code = c.emit(code, in)
+		}
+		return code
+	}
+
+	in := instruction{op: opWord, name: word}
+	for i, mode := range modes {
+		number, err := parseValue(word, mode, 0)
+		in.numbers[i], in.parsed[i] = number, err == nil
+	}
+	// the word may call a macro that changes the mode or precision
+	c.mode = ""
+	c.precision = -1
+	return append(code, in)
+}
+
+func isSpecialForm(word string) bool {
+	token, ok := parseKeyword(strings.TrimSpace(word))
+	return ok && (token.Type == REPEAT || token.Type == MACRODEF || token.Type == DISASM)
+}
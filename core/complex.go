@@ -0,0 +1,238 @@
+package core
+
+import (
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+// complexNumber -> a complex number, read and shown in dec mode only
+type complexNumber complex128
+
+// parseComplex -> parse a complex literal written as 3+4i or (3,4)
+func parseComplex(item, mode string, digits int) (Value, bool) {
+	if mode != DEC {
+		return nil, false
+	}
+	if strings.HasPrefix(item, "(") && strings.HasSuffix(item, ")") {
+		parts := strings.Split(item[1:len(item)-1], ",")
+		if len(parts) != 2 {
+			return nil, false
+		}
+		re, err1 := strconv.ParseFloat(parts[0], 64)
+		im, err2 := strconv.ParseFloat(parts[1], 64)
+		return complexNumber(complex(re, im)), err1 == nil && err2 == nil
+	}
+	if !strings.HasSuffix(item, "i") {
+		return nil, false
+	}
+	c, err := strconv.ParseComplex(item, 128)
+	return complexNumber(c), err == nil
+}
+
+func (c complexNumber) Kind() Kind {
+	return Complex
+}
+
+func (x complexNumber) show(c *Calculator) string {
+	re := strconv.FormatFloat(real(x), 'f', -1, 64)
+	im := strconv.FormatFloat(imag(x), 'f', -1, 64)
+	if !strings.HasPrefix(im, "-") {
+		im = "+" + im
+	}
+	return re + im + "i"
+}
+
+func (c complexNumber) Equal(other Value) bool {
+	if d, ok := other.(complexNumber); ok {
+		return c == d
+	}
+	// a complex number with no imaginary part is equal to the real number it stands for
+	return imag(complex128(c)) == 0 && numericEqual(float(real(complex128(c))), other)
+}
+
+// handleComplexParts -> build complex numbers from real ones and take them apart, whatever the operands are
+func (c *Calculator) handleComplexParts(token Token) bool {
+	switch token.Type {
+	case RTOC:
+		op1 := c.popNumber(RTOC)
+		op2 := c.popNumber(RTOC)
+		c.pushComplex(complex(op2, op1))
+	case RECT:
+		op1 := c.popNumber(RECT)
+		op2 := c.popNumber(RECT)
+		c.pushComplex(cmplx.Rect(op2, op1))
+	case CTOR:
+		op1 := c.popComplex(CTOR)
+		c.push(float(real(op1)))
+		c.push(float(imag(op1)))
+	case POLAR:
+		r, theta := cmplx.Polar(c.popComplex(POLAR))
+		c.push(float(r))
+		c.push(float(theta))
+	case RE:
+		c.push(float(real(c.popComplex(RE))))
+	case IM:
+		c.push(float(imag(c.popComplex(IM))))
+	case ARG:
+		c.push(float(cmplx.Phase(c.popComplex(ARG))))
+	case CONJ:
+		c.pushComplex(cmplx.Conj(c.popComplex(CONJ)))
+	default:
+		return false
+	}
+	return true
+}
+
+// promoteComplex -> run a command on complex numbers when it would give NaN for its real operands
+func (c *Calculator) promoteComplex(token Token) bool {
+	return c.promote && c.outOfDomain(token.Type) && c.handleComplex(token)
+}
+
+// handleComplex -> run a command on complex numbers when one of its operands is complex
+func (c *Calculator) handleComplex(token Token) bool {
+	switch token.Type {
+	case PLUS:
+		op1 := c.popComplex(PLUS)
+		op2 := c.popComplex(PLUS)
+		c.pushComplex(op2 + op1)
+	case MINUS:
+		op1 := c.popComplex(MINUS)
+		op2 := c.popComplex(MINUS)
+		c.pushComplex(op2 - op1)
+	case MULTIPLY:
+		op1 := c.popComplex(MULTIPLY)
+		op2 := c.popComplex(MULTIPLY)
+		c.pushComplex(op2 * op1)
+	case DIVIDE:
+		op1 := c.popComplex(DIVIDE)
+		op2 := c.popComplex(DIVIDE)
+		c.pushComplex(op2 / op1)
+	case POW:
+		op1 := c.popComplex(POW)
+		op2 := c.popComplex(POW)
+		c.pushComplex(complexPow(op2, op1))
+	case DECR:
+		c.pushComplex(c.popComplex(DECR) - 1)
+	case INCR:
+		c.pushComplex(c.popComplex(INCR) + 1)
+	case EQ:
+		op1 := c.popComplex(EQ)
+		op2 := c.popComplex(EQ)
+		c.push(boolean(op2 == op1))
+	case NOTEQ:
+		op1 := c.popComplex(NOTEQ)
+		op2 := c.popComplex(NOTEQ)
+		c.push(boolean(op2 != op1))
+	case ABS:
+		c.push(float(cmplx.Abs(c.popComplex(ABS))))
+
+	case ACOS:
+		c.pushComplex(cmplx.Acos(c.popComplex(ACOS)))
+	case ASIN:
+		c.pushComplex(cmplx.Asin(c.popComplex(ASIN)))
+	case ATAN:
+		c.pushComplex(cmplx.Atan(c.popComplex(ATAN)))
+	case COS:
+		c.pushComplex(cmplx.Cos(c.popComplex(COS)))
+	case COSH:
+		c.pushComplex(cmplx.Cosh(c.popComplex(COSH)))
+	case SIN:
+		c.pushComplex(cmplx.Sin(c.popComplex(SIN)))
+	case SINH:
+		c.pushComplex(cmplx.Sinh(c.popComplex(SINH)))
+	case TANH:
+		c.pushComplex(cmplx.Tanh(c.popComplex(TANH)))
+
+	case EXP:
+		c.pushComplex(cmplx.Exp(c.popComplex(EXP)))
+	case SQRT:
+		c.pushComplex(cmplx.Sqrt(c.popComplex(SQRT)))
+	case LN:
+		c.pushComplex(cmplx.Log(c.popComplex(LN)))
+	case LOG:
+		c.pushComplex(cmplx.Log10(c.popComplex(LOG)))
+	default:
+		return false
+	}
+	return true
+}
+
+// complexPow -> a power of a complex number, by repeated multiplication when the exponent is a whole number so that
+// exact operands give exact results. Powers too large for repeated multiplication, which gives NaN once the parts of
+// the product overflow, are worked out in polar form instead.
+func complexPow(base, exponent complex128) complex128 {
+	n := real(exponent)
+	if imag(exponent) != 0 || n != math.Trunc(n) || math.Abs(n) > 1<<53 {
+		return cmplx.Pow(base, exponent)
+	}
+	result, square := complex(1, 0), base
+	for k := int64(math.Abs(n)); k > 0; k >>= 1 {
+		if k&1 == 1 {
+			result *= square
+		}
+		square *= square
+	}
+	if cmplx.IsNaN(result) || cmplx.IsInf(result) {
+		if imag(base) == 0 {
+			// the power of a real number is real, where the polar form would give an imaginary part of Inf times 0
+			return complex(math.Pow(real(base), n), 0)
+		}
+		result = cmplx.Pow(base, exponent)
+	} else if n < 0 {
+		result = 1 / result
+	}
+	// adding zero turns the negative zeros of products such as i*i*i into zeros
+	return result + 0
+}
+
+// outOfDomain -> whether a real command would give NaN for the operands on top of the stack
+func (c *Calculator) outOfDomain(command string) bool {
+	switch command {
+	case SQRT, LN, LOG:
+		x, _ := numberValue(c.stack[len(c.stack)-1])
+		return x < 0
+	case ACOS, ASIN:
+		x, _ := numberValue(c.stack[len(c.stack)-1])
+		return math.Abs(x) > 1
+	case POW:
+		exponent, _ := numberValue(c.stack[len(c.stack)-1])
+		base, _ := numberValue(c.stack[len(c.stack)-2])
+		return base < 0 && exponent != math.Trunc(exponent)
+	}
+	return false
+}
+
+// anyOperand -> whether any of the operands of a command on top of the stack has the given type
+func (c *Calculator) anyOperand(command string, kind Kind) bool {
+	arity := len(effects[command].in)
+	if len(c.stack) < arity {
+		return false
+	}
+	for _, item := range c.stack[len(c.stack)-arity:] {
+		if item.Kind() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Calculator) pushComplex(x complex128) {
+	c.push(complexNumber(x))
+}
+
+func (c *Calculator) popComplex(command string) complex128 {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	if x, ok := item.(complexNumber); ok {
+		return complex128(x)
+	}
+	number, ok := numberValue(item)
+	if !ok {
+		throwWrongElementType(Number, item.Kind())
+	}
+	return complex(number, 0)
+}
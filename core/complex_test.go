@@ -0,0 +1,39 @@
+package core
+
+import "testing"
+
+func TestComplex(t *testing.T) {
+	testResults(t, []resultTest{
+		{"3+4i 3-4i *", "25+0i"},
+		{"1+2i 3-4i /", "-0.2+0.4i"},
+		{"(1,2) 1 +", "2+2i"},
+		{"(3,4) conj", "3-4i"},
+		{"3+4i abs", "5"},
+		{"3+4i re", "3"},
+		{"3+4i im", "4"},
+		{"0+1i arg", "1.5707963267948966"},
+		{"0+2i polar", "2, 1.5707963267948966"},
+		{"3 4 r->c c->r", "3, 4"},
+		{"2+0i 2 ==", "true"},
+		{"2 2+0i ==", "true"},
+		{"2+1i 2 ==", "false"},
+
+		// whole powers are exact, and overflow like real powers do
+		{"0+1i 0+1i *", "-1+0i"},
+		{"1+1i 2 pow", "0+2i"},
+		{"0+1i 4 pow", "1+0i"},
+		{"0+1i 2000 pow", "1+0i"},
+		{"1+1i -2 pow", "0-0.5i"},
+		{"1.5+0i 2000 pow", "+Inf+0i"},
+		{"-1.5+0i 2001 pow", "-Inf+0i"},
+		{"1.5+0i -2000 pow", "0+0i"},
+
+		// real commands give NaN out of their domain unless they promote to complex numbers
+		{"-1 sqrt", "NaN"},
+		{"complex -4 sqrt", "0+2i"},
+		{"complex -1 ln", "0+3.141592653589793i"},
+		{"complex 2 acos", "0-1.3169578969248164i"},
+		{"complex -8 1/3 pow", "1+1.732050807568877i"},
+		{"complex 2 3 +", "5"},
+	})
+}
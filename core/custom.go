@@ -0,0 +1,176 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Op -> a command added to a calculator by a program that embeds it
+type Op struct {
+	// Name -> how the command is spelled. Registering a command with the name of an existing one replaces it, and
+	// one with the alias of an existing command takes the alias from it.
+	Name    string
+	Aliases []string
+	// Arity -> the number of items the command pops
+	Arity int
+	// Args -> the type of each item the command pops, bottom first, or nil if they are all numbers. Any takes
+	// every type, and Number takes every real number.
+	Args []Kind
+	Help string
+	// Examples -> calculations using the command, whose results Docs works out by running them
+	Examples []string
+	// Fn -> the items to push, bottom first, given the items popped, bottom first. An error fails the calculation
+	// like the errors of the built-in commands do.
+	Fn func(args []Value) ([]Value, error)
+}
+
+// Register -> add a command to the calculator, replacing any command with the same name
+func (c *Calculator) Register(op Op) error {
+	if err := c.registrable(op); err != nil {
+		return err
+	}
+	if _, ok := c.operators[op.Name]; ok {
+		c.remove(op.Name)
+	}
+
+	c.own()
+	// the name no longer stands for the command it was an alias of
+	delete(c.aliases, op.Name)
+	custom := &operator{name: op.Name, aliases: op.Aliases, impure: true, help: op.Help, examples: op.Examples,
+		run: op.run, custom: true, args: op.args()}
+	c.operators[op.Name] = custom
+	for _, alias := range op.Aliases {
+		c.aliases[alias] = op.Name
+	}
+	c.custom = append(c.custom, custom)
+	c.compiled = make(map[compiledKey][]instruction)
+	return nil
+}
+
+// Disable -> remove a command from the calculator, by its name or any of its aliases
+func (c *Calculator) Disable(name string) error {
+	token, ok := c.keyword(name)
+	if !ok {
+		return fmt.Errorf("Unknown command: %v", name)
+	}
+	c.remove(token.Type)
+	c.compiled = make(map[compiledKey][]instruction)
+	return nil
+}
+
+// registrable -> whether a command can be registered with the calculator
+func (c *Calculator) registrable(op Op) error {
+	if err := op.check(); err != nil {
+		return err
+	}
+	for _, alias := range op.Aliases {
+		if token, ok := c.keyword(alias); ok && token.Type != op.Name {
+			return fmt.Errorf("Already a command: %v", alias)
+		}
+	}
+	return nil
+}
+
+// remove -> remove a command and its aliases
+func (c *Calculator) remove(name string) {
+	c.own()
+	delete(c.operators, name)
+	for alias, canonical := range c.aliases {
+		if canonical == name {
+			delete(c.aliases, alias)
+		}
+	}
+	for i, op := range c.custom {
+		if op.name == name {
+			c.custom = append(c.custom[:i:i], c.custom[i+1:]...)
+			break
+		}
+	}
+}
+
+// own -> copy the tables of commands the calculator shares with the built-in ones, before changing them
+func (c *Calculator) own() {
+	if c.owned {
+		return
+	}
+	operators := make(map[string]*operator, len(c.operators))
+	for name, op := range c.operators {
+		operators[name] = op
+	}
+	aliases := make(map[string]string, len(c.aliases))
+	for alias, name := range c.aliases {
+		aliases[alias] = name
+	}
+	c.operators, c.aliases, c.owned = operators, aliases, true
+}
+
+// check -> whether a command can be registered
+func (op Op) check() error {
+	for _, name := range append([]string{op.Name}, op.Aliases...) {
+		if name == "" || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("Invalid command name: %q", name)
+		}
+	}
+	if op.Fn == nil {
+		return fmt.Errorf("No implementation: %v", op.Name)
+	}
+	if op.Arity < 0 || (op.Args != nil && len(op.Args) != op.Arity) {
+		return fmt.Errorf("Expected %v argument types but found %v: %v", op.Arity, len(op.Args), op.Name)
+	}
+	return nil
+}
+
+// args -> the type of each item the command pops, bottom first
+func (op Op) args() []Kind {
+	if op.Args != nil {
+		return op.Args
+	}
+	args := make([]Kind, op.Arity)
+	for i := range args {
+		args[i] = Number
+	}
+	return args
+}
+
+// run -> check the types of the operands of a registered command, and replace them with its results
+func (op Op) run(c *Calculator, token Token) {
+	if len(c.stack) < op.Arity {
+		throwNotEnoughElementsError(op.Name)
+	}
+	args := append([]Value(nil), c.stack[len(c.stack)-op.Arity:]...)
+	for i, arg := range args {
+		expected := Number
+		if op.Args != nil {
+			expected = op.Args[i]
+		}
+		if !accepts(expected, arg) {
+			throwWrongElementType(expected, arg.Kind())
+		}
+	}
+	results, err := op.Fn(args)
+	if err != nil {
+		throw("%v: %v", err, op.Name)
+	}
+	for _, result := range results {
+		if result == nil {
+			throw("The command pushed nothing: %v", op.Name)
+		}
+	}
+	c.stack = c.stack[:len(c.stack)-op.Arity]
+	for _, result := range results {
+		c.push(result)
+	}
+}
+
+// accepts -> whether an item has the type a command expects
+func accepts(expected Kind, item Value) bool {
+	switch expected {
+	case Any:
+		return true
+	case Number:
+		_, ok := numberValue(item)
+		return ok
+	}
+	return item.Kind() == expected
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+// vat -> a registered command that adds 20% to a number
+var vat = Op{Name: "vat", Arity: 1, Help: "add VAT", Fn: func(args []Value) ([]Value, error) {
+	x, _ := numberValue(args[0])
+	return []Value{float(x * 1.2)}, nil
+}}
+
+// pushes -> a registered command that pushes a number, popping items of the given types
+func pushes(name string, x float64, args ...Kind) Op {
+	return Op{Name: name, Arity: len(args), Args: args, Fn: func([]Value) ([]Value, error) {
+		return []Value{float(x)}, nil
+	}}
+}
+
+// listed -> whether the help lists a command
+func listed(help, name string) bool {
+	return regexp.MustCompile(`\t` + regexp.QuoteMeta(name) + ` *= `).MatchString(help)
+}
+
+// evalShown -> the items a line leaves on the stack of a calculator as they are shown, or the error it fails with
+func evalShown(c *Calculator, line string) string {
+	if err := c.Eval(line); err != nil {
+		return "error: " + err.Error()
+	}
+	return fmt.Sprint(c.Show(list(c.Stack())))
+}
+
+func TestRegister(t *testing.T) {
+	c := New()
+	ops := []Op{
+		vat,
+		// in place of a built-in command, and of an alias, which the command it stood for loses
+		pushes("+", 42, Any, Any),
+		pushes("ceiling", 7, Number),
+		{Name: "upper2", Arity: 1, Args: []Kind{String}, Fn: func(args []Value) ([]Value, error) {
+			return []Value{args[0], args[0]}, nil
+		}},
+		{Name: "fails", Fn: func([]Value) ([]Value, error) { return nil, errors.New("Out of stock") }},
+		{Name: "nothing", Fn: func([]Value) ([]Value, error) { return []Value{nil}, nil }},
+	}
+	for _, op := range ops {
+		if err := c.Register(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []resultTest{
+		{"100 vat", "{ 120 }"},
+		{"100 vat vat", "{ 144 }"},
+		{"1/2 vat", "{ 0.6 }"},
+		{`"a" 1 +`, "{ 42 }"},
+		{"1.5 ceiling", "{ 7 }"},
+		{"1.5 ceil", "{ 2 }"},
+		{`"ab" upper2`, `{ "ab" "ab" }`},
+		{"macro twice vat vat", "{ }"},
+		{"100 twice", "{ 144 }"},
+		{"vat", "error: Not enough items on the stack to perform this command: vat"},
+		{`"a" vat`, "error: Expected a number on the stack but found a string"},
+		{"1 upper2", "error: Expected a string on the stack but found an integer"},
+		{"fails", "error: Out of stock: fails"},
+		{"nothing", "error: The command pushed nothing: nothing"},
+	}
+	for _, test := range tests {
+		c.stack = nil
+		if got := evalShown(c, test.line); got != test.expected {
+			t.Errorf("%q: got %v, expected %v", test.line, got, test.expected)
+		}
+	}
+
+	// a failed command leaves the stack as it was
+	c.stack = []Value{float(1), float(2)}
+	if err := c.Eval("fails"); err == nil || c.Show(list(c.Stack())) != "{ 1 2 }" {
+		t.Errorf("fails: got %v and %v", c.Stack(), err)
+	}
+
+	// registering a command again replaces it in macros that were compiled before
+	if err := c.Register(pushes("vat", 5, Number)); err != nil {
+		t.Fatal(err)
+	}
+	c.stack = nil
+	if got := evalShown(c, "100 twice"); got != "{ 5 }" {
+		t.Errorf("twice after registering vat again: got %v", got)
+	}
+
+	help := c.Help()
+	if !listed(help, "vat") || !listed(help, "+") || !listed(help, "ceil") {
+		t.Errorf("expected vat, + and ceil in the help:\n%v", help)
+	}
+	if New().Eval("100 vat") == nil {
+		t.Error("registering a command changed the other calculators")
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	fn := vat.Fn
+	tests := []struct {
+		op  Op
+		err string
+	}{
+		{Op{Name: "", Fn: fn}, `Invalid command name: ""`},
+		{Op{Name: "two words", Fn: fn}, `Invalid command name: "two words"`},
+		{Op{Name: "vat", Aliases: []string{"v a t"}, Fn: fn}, `Invalid command name: "v a t"`},
+		{Op{Name: "vat"}, "No implementation: vat"},
+		{Op{Name: "vat", Arity: -1, Fn: fn}, "Expected -1 argument types but found 0: vat"},
+		{Op{Name: "vat", Arity: 2, Args: []Kind{Number}, Fn: fn}, "Expected 2 argument types but found 1: vat"},
+		{Op{Name: "vat", Aliases: []string{"ceiling"}, Fn: fn}, "Already a command: ceiling"},
+		{Op{Name: "vat", Aliases: []string{"+"}, Fn: fn}, "Already a command: +"},
+	}
+	for _, test := range tests {
+		c := New()
+		if err := c.Register(test.op); err == nil || err.Error() != test.err {
+			t.Errorf("%+v: got %v, expected %v", test.op, err, test.err)
+		}
+		// nothing is registered when the command is rejected
+		if c.Eval("1 vat") == nil {
+			t.Errorf("%+v was registered", test.op)
+		}
+	}
+}
+
+func TestDisable(t *testing.T) {
+	c := New()
+	if err := c.Eval("macro add +"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Eval("1 2 add"); err != nil {
+		t.Fatal(err)
+	}
+	// by name, and by an alias along with the command's other spellings
+	for _, name := range []string{"exit", "+", "ceiling"} {
+		if err := c.Disable(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Disable("frobnicate"); err == nil || err.Error() != "Unknown command: frobnicate" {
+		t.Errorf("frobnicate: got %v", err)
+	}
+
+	tests := []resultTest{
+		{"exit", "error: Unknown command: exit"},
+		{"1 2 +", "error: Unknown command: +"},
+		{"1 2 add", "error: Unknown command: +"},
+		{"1.5 ceil", "error: Unknown command: ceil"},
+		{"1.5 ceiling", "error: Unknown command: ceiling"},
+		{"1 2 -", "{ -1 }"},
+	}
+	for _, test := range tests {
+		c.stack = nil
+		if got := evalShown(c, test.line); got != test.expected {
+			t.Errorf("%q: got %v, expected %v", test.line, got, test.expected)
+		}
+	}
+
+	help := c.Help()
+	for _, name := range []string{"exit", "+", "ceil"} {
+		if listed(help, name) {
+			t.Errorf("%v is still in the help", name)
+		}
+	}
+	if !listed(help, "-") || !listed(New().Help(), "exit") {
+		t.Errorf("expected - in the help:\n%v", help)
+	}
+	if err := New().Eval("1.5 ceiling"); err != nil {
+		t.Errorf("disabling a command changed the other calculators: %v", err)
+	}
+}
+
+// TestCheckCustom -> scripts are checked against the commands of the calculator that checks them
+func TestCheckCustom(t *testing.T) {
+	c := New()
+	if err := c.Register(pushes("label", 1, String)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Disable("exit"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		script   string
+		problems []string
+	}{
+		{`"a" label 1 +`, nil},
+		{"1 label", []string{"s.rpn:1: Expected a string on the stack but found a number: label"}},
+		{"label", []string{"s.rpn:1: Not enough items on the stack to perform this command: label"}},
+		{"1 exit", []string{"s.rpn:1: Unknown command: exit"}},
+		{"macro label 1", []string{"s.rpn:1: Macro label can never be called because it is a built-in command"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, p := range c.checkScript(parseScript("s.rpn", test.script)) {
+			got = append(got, fmt.Sprintf("%v: %v", p.pos, p.msg))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.problems) {
+			t.Errorf("%q: got %q, expected %q", test.script, got, test.problems)
+		}
+	}
+	// the built-in commands are checked as before
+	if problems := New().checkScript(parseScript("s.rpn", "1 label")); len(problems) != 1 {
+		t.Errorf("1 label: got %v", problems)
+	}
+}
@@ -0,0 +1,215 @@
+package core
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// decimal -> a base 10 number in decimal mode, kept exactly until it has to be rounded to the scale
+type decimal struct {
+	value *big.Rat
+}
+
+// parseDecimal -> parse a number literal in decimal mode
+func parseDecimal(item, mode string, digits int) (Value, bool) {
+	if mode != DECIMAL {
+		return nil, false
+	}
+	if _, err := strconv.ParseFloat(item, 64); err != nil {
+		return nil, false
+	}
+	x, ok := new(big.Rat).SetString(item)
+	return decimal{x}, ok
+}
+
+func (d decimal) Kind() Kind {
+	return Decimal
+}
+
+func (d decimal) show(c *Calculator) string {
+	return c.roundDecimal(d.value).FloatString(c.scale)
+}
+
+func (d decimal) Equal(other Value) bool {
+	return numericEqual(d, other)
+}
+
+// handleDecimal -> run a command on decimals and integers in base 10, returning false when it isn't a decimal operation
+// so that the operands are promoted to floats instead
+func (c *Calculator) handleDecimal(token Token) bool {
+	switch token.Type {
+	case PLUS:
+		op1 := c.popDecimal(PLUS)
+		op2 := c.popDecimal(PLUS)
+		c.pushDecimal(new(big.Rat).Add(op2, op1))
+	case MINUS:
+		op1 := c.popDecimal(MINUS)
+		op2 := c.popDecimal(MINUS)
+		c.pushDecimal(new(big.Rat).Sub(op2, op1))
+	case MULTIPLY:
+		op1 := c.popDecimal(MULTIPLY)
+		op2 := c.popDecimal(MULTIPLY)
+		c.pushDecimal(c.roundDecimal(new(big.Rat).Mul(op2, op1)))
+	case DIVIDE:
+		op1 := c.popDecimal(DIVIDE)
+		op2 := c.popDecimal(DIVIDE)
+		if op1.Sign() == 0 {
+			throwDivisionByZeroError(DIVIDE)
+		}
+		c.pushDecimal(c.roundDecimal(new(big.Rat).Quo(op2, op1)))
+	case MOD:
+		op1 := c.popDecimal(MOD)
+		op2 := c.popDecimal(MOD)
+		if op1.Sign() == 0 {
+			throwDivisionByZeroError(MOD)
+		}
+		quotient := new(big.Rat).SetInt(ratTrunc(new(big.Rat).Quo(op2, op1)))
+		c.pushDecimal(new(big.Rat).Sub(op2, quotient.Mul(quotient, op1)))
+	case POW:
+		// only whole powers are worked out exactly
+		if exponent, _ := exactValue(c.stack[len(c.stack)-1]); !exponent.IsInt() || !exponent.Num().IsInt64() {
+			return false
+		}
+		n := c.popDecimal(POW).Num().Int64()
+		op2 := c.popDecimal(POW)
+		magnitude := uint64(n)
+		if n < 0 {
+			magnitude = -magnitude
+		}
+		// the numerator and denominator have no common factors, so neither do their powers
+		result := new(big.Rat).SetFrac(c.power(op2.Num(), magnitude), c.power(op2.Denom(), magnitude))
+		if n < 0 {
+			if result.Sign() == 0 {
+				throwDivisionByZeroError(POW)
+			}
+			result.Inv(result)
+		}
+		c.pushDecimal(c.roundDecimal(result))
+	case DECR:
+		op1 := c.popDecimal(DECR)
+		c.pushDecimal(new(big.Rat).Sub(op1, big.NewRat(1, 1)))
+	case INCR:
+		op1 := c.popDecimal(INCR)
+		c.pushDecimal(new(big.Rat).Add(op1, big.NewRat(1, 1)))
+
+	case LT:
+		op1 := c.popDecimal(LT)
+		op2 := c.popDecimal(LT)
+		c.push(boolean(op2.Cmp(op1) < 0))
+	case LTOREQ:
+		op1 := c.popDecimal(LTOREQ)
+		op2 := c.popDecimal(LTOREQ)
+		c.push(boolean(op2.Cmp(op1) <= 0))
+	case NOTEQ:
+		op1 := c.popDecimal(NOTEQ)
+		op2 := c.popDecimal(NOTEQ)
+		c.push(boolean(op2.Cmp(op1) != 0))
+	case EQ:
+		op1 := c.popDecimal(EQ)
+		op2 := c.popDecimal(EQ)
+		c.push(boolean(op2.Cmp(op1) == 0))
+	case GT:
+		op1 := c.popDecimal(GT)
+		op2 := c.popDecimal(GT)
+		c.push(boolean(op2.Cmp(op1) > 0))
+	case GTOREQ:
+		op1 := c.popDecimal(GTOREQ)
+		op2 := c.popDecimal(GTOREQ)
+		c.push(boolean(op2.Cmp(op1) >= 0))
+
+	case CEIL:
+		op1 := c.popDecimal(CEIL)
+		ceil := ratFloor(op1.Neg(op1))
+		c.pushDecimal(new(big.Rat).SetInt(ceil.Neg(ceil)))
+	case FLOOR:
+		c.pushDecimal(new(big.Rat).SetInt(ratFloor(c.popDecimal(FLOOR))))
+	case ROUND:
+		op1 := c.popDecimal(ROUND)
+		half := new(big.Rat).Abs(op1)
+		rounded := ratFloor(half.Add(half, big.NewRat(1, 2)))
+		if op1.Sign() < 0 {
+			rounded.Neg(rounded)
+		}
+		c.pushDecimal(new(big.Rat).SetInt(rounded))
+	case IP:
+		c.pushDecimal(new(big.Rat).SetInt(ratTrunc(c.popDecimal(IP))))
+	case FP:
+		op1 := c.popDecimal(FP)
+		c.pushDecimal(new(big.Rat).Sub(op1, new(big.Rat).SetInt(ratTrunc(op1))))
+	case SIGN:
+		op1 := c.popDecimal(SIGN)
+		c.pushInteger(big.NewInt(int64(op1.Sign())))
+	case ABS:
+		c.pushDecimal(new(big.Rat).Abs(c.popDecimal(ABS)))
+	case MAX:
+		op1 := c.popDecimal(MAX)
+		op2 := c.popDecimal(MAX)
+		if op2.Cmp(op1) > 0 {
+			op1 = op2
+		}
+		c.pushDecimal(op1)
+	case MIN:
+		op1 := c.popDecimal(MIN)
+		op2 := c.popDecimal(MIN)
+		if op2.Cmp(op1) < 0 {
+			op1 = op2
+		}
+		c.pushDecimal(op1)
+	default:
+		return false
+	}
+	return true
+}
+
+// roundDecimal -> round to the scale using the rounding mode
+func (c *Calculator) roundDecimal(x *big.Rat) *big.Rat {
+	unit := c.power(big.NewInt(10), uint64(c.scale))
+	scaled := new(big.Rat).Mul(x, new(big.Rat).SetInt(unit))
+	if scaled.IsInt() {
+		return x
+	}
+
+	whole := ratFloor(scaled)
+	rest := new(big.Rat).Sub(scaled, new(big.Rat).SetInt(whole))
+	up := false
+	switch c.rounding {
+	case "down":
+		up = scaled.Sign() < 0
+	case "ceiling":
+		up = true
+	case "half-up":
+		switch rest.Cmp(big.NewRat(1, 2)) {
+		case 1:
+			up = true
+		case 0:
+			up = scaled.Sign() > 0
+		}
+	default:
+		switch rest.Cmp(big.NewRat(1, 2)) {
+		case 1:
+			up = true
+		case 0:
+			up = whole.Bit(0) == 1
+		}
+	}
+	if up {
+		whole.Add(whole, big.NewInt(1))
+	}
+	return new(big.Rat).SetFrac(whole, unit)
+}
+
+func (c *Calculator) pushDecimal(x *big.Rat) {
+	c.push(decimal{x})
+}
+
+func (c *Calculator) popDecimal(command string) *big.Rat {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	x, ok := exactValue(item)
+	if !ok {
+		throwWrongElementType(Decimal, item.Kind())
+	}
+	return x
+}
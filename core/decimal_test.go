@@ -0,0 +1,30 @@
+package core
+
+import "testing"
+
+func TestDecimal(t *testing.T) {
+	testResults(t, []resultTest{
+		{"decimal 0.1 0.2 +", "0.30"},
+		{"decimal 0.1 0.2 + 0.3 ==", "true"},
+		{"decimal 0.30 0.3 -", "0.00"},
+		{"decimal 123456789012345678.91 0.01 +", "123456789012345678.92"},
+		{"decimal 2.5 1 *", "2.50"},
+		{"decimal 1.1 2 pow", "1.21"},
+		{"decimal 0.1 -2 pow", "100.00"},
+		{"decimal 1 3 /", "0.33"},
+		{"decimal 5 scale 1 3 /", "0.33333"},
+		{"decimal 10 3 / 3 *", "9.99"},
+		{"decimal 1 0 /", "error: Division by zero: /"},
+
+		// rounding to the scale, half to even by default
+		{"decimal 1.05 1.05 *", "1.10"},
+		{"decimal 0 scale 2.5 1 *", "2"},
+		{"decimal 0 scale 3.5 1 *", "4"},
+		{"decimal 0 scale round-half-up 2.5 1 *", "3"},
+		{"decimal 0 scale round-half-up -2.5 1 *", "-3"},
+		{"decimal 0 scale round-down 2 3 /", "0"},
+		{"decimal 0 scale round-down -2 3 /", "0"},
+		{"decimal 0 scale round-ceiling 2.1 1 *", "3"},
+		{"decimal 0 scale round-ceiling -2.5 1 *", "-2"},
+	})
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+type diffLine struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// unifiedDiff -> a unified diff between two versions of a file, like diff -u
+func unifiedDiff(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "diff %v.orig %v\n--- %v.orig\n+++ %v\n", name, name, name, name)
+	for start := 0; start < len(lines); {
+		// find the next change and the end of the hunk around it
+		for start < len(lines) && lines[start].kind == ' ' {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		from := start - diffContext
+		if from < 0 {
+			from = 0
+		}
+		end, unchanged := start, 0
+		for end < len(lines) && unchanged <= 2*diffContext {
+			if lines[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			end++
+		}
+		if unchanged > diffContext {
+			end -= unchanged - diffContext
+		}
+
+		aStart, bStart := 1, 1
+		for _, line := range lines[:from] {
+			if line.kind != '+' {
+				aStart++
+			}
+			if line.kind != '-' {
+				bStart++
+			}
+		}
+		aLen, bLen := 0, 0
+		for _, line := range lines[from:end] {
+			if line.kind != '+' {
+				aLen++
+			}
+			if line.kind != '-' {
+				bLen++
+			}
+		}
+		if aLen == 0 {
+			aStart--
+		}
+		if bLen == 0 {
+			bStart--
+		}
+
+		fmt.Fprintf(&out, "@@ -%v,%v +%v,%v @@\n", aStart, aLen, bStart, bLen)
+		for _, line := range lines[from:end] {
+			fmt.Fprintf(&out, "%c%v\n", line.kind, line.text)
+		}
+		start = end
+	}
+	return out.String()
+}
+
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines -> the edits that turn a into b, from their longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		} else if common[i+1][j] >= common[i][j+1] {
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		} else {
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
@@ -0,0 +1,41 @@
+package core
+
+// effect -> the types a command pops from the stack and pushes back, bottom first
+type effect struct {
+	in  []Kind
+	out []Kind
+	// complex -> what the command gives when it runs on a complex number where it takes a number: Complex, a real
+	// Number, or Any for commands that only take real numbers
+	complex Kind
+}
+
+var (
+	unaryNumber  = &effect{in: []Kind{Number}, out: []Kind{Number}}
+	binaryNumber = &effect{in: []Kind{Number, Number}, out: []Kind{Number}}
+	// unaryComplex, binaryComplex -> numeric commands that also run on complex numbers, giving complex numbers back
+	unaryComplex  = &effect{in: []Kind{Number}, out: []Kind{Number}, complex: Complex}
+	binaryComplex = &effect{in: []Kind{Number, Number}, out: []Kind{Number}, complex: Complex}
+	// complexPart -> a real number worked out from a real or complex number
+	complexPart  = &effect{in: []Kind{Number}, out: []Kind{Number}, complex: Number}
+	complexParts = &effect{in: []Kind{Number}, out: []Kind{Number, Number}, complex: Number}
+	makeComplex  = &effect{in: []Kind{Number, Number}, out: []Kind{Complex}}
+	unaryBool    = &effect{in: []Kind{Boolean}, out: []Kind{Boolean}}
+	binaryBool   = &effect{in: []Kind{Boolean, Boolean}, out: []Kind{Boolean}}
+	comparison   = &effect{in: []Kind{Number, Number}, out: []Kind{Boolean}}
+	equality     = &effect{in: []Kind{Any, Any}, out: []Kind{Boolean}}
+	noEffect     = &effect{}
+)
+
+// numeric -> whether a command takes and gives single numbers, so that it runs element by element on vectors and
+// matrices
+func (e effect) numeric() bool {
+	if len(e.out) != 1 || (e.out[0] != Number && e.out[0] != Complex && e.out[0] != Measurement) {
+		return false
+	}
+	for _, in := range e.in {
+		if in != Number {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,256 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const indent = "    "
+
+// sourceLine -> a line of a script split into its commands and its comment, and how many blocks and vectors it is
+// inside of
+type sourceLine struct {
+	words   []string
+	comment string
+	depth   int
+}
+
+func (l sourceLine) isTest() bool {
+	return l.depth == 0 && len(l.words) > 0 && strings.HasPrefix(l.words[0], testPrefix)
+}
+
+// level -> how far the line is indented past the test: block it is in. A line that closes a block or vector lines
+// up with the line that opened it.
+func (l sourceLine) level() int {
+	if l.depth > 0 && len(l.words) > 0 && (l.words[0] == "}" || strings.HasPrefix(l.words[0], "]")) {
+		return l.depth - 1
+	}
+	return l.depth
+}
+
+// Fmt -> format the rpn scripts at the given paths, or standard input if there are none
+func Fmt(paths []string, write, diff bool) (bool, error) {
+	if len(paths) == 0 {
+		if write {
+			return false, fmt.Errorf("Cannot use -w with standard input")
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return false, err
+		}
+		formatted := Format(string(src))
+		if diff {
+			fmt.Print(unifiedDiff("<standard input>", string(src), formatted))
+		} else {
+			fmt.Print(formatted)
+		}
+		return true, nil
+	}
+
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || (file != path && !strings.HasSuffix(file, ".rpn")) {
+				return nil
+			}
+			return fmtFile(file, info.Mode(), write, diff)
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func fmtFile(file string, perm os.FileMode, write, diff bool) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	src := string(data)
+	formatted := Format(src)
+
+	if diff && formatted != src {
+		fmt.Print(unifiedDiff(file, src, formatted))
+	}
+	if write && formatted != src {
+		return ioutil.WriteFile(file, []byte(formatted), perm)
+	}
+	if !write && !diff {
+		fmt.Print(formatted)
+	}
+	return nil
+}
+
+// Format -> canonicalise the layout of an rpn script. Formatting is idempotent.
+// Commands are separated by single spaces, aliases are replaced by the command they stand for, inside blocks too,
+// the bodies of test: blocks and the lines of blocks and vectors spread over several lines are indented and runs of
+// blank lines are collapsed.
+func Format(src string) string {
+	var lines []sourceLine
+	var blankBefore []bool
+	blank := false
+	depth := 0
+	for _, text := range strings.Split(src, "\n") {
+		code, comment := splitComment(text)
+		line := sourceLine{words: blockWords(code), comment: comment, depth: depth}
+		depth = nesting(depth, code)
+		if len(line.words) == 0 && comment == "" {
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blankBefore = append(blankBefore, blank && len(lines) > 1)
+		blank = false
+	}
+
+	// comments directly above a test: belong to it rather than to the block before it
+	attached := make([]bool, len(lines))
+	attach := false
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i].isTest() {
+			attach = !blankBefore[i]
+		} else if len(lines[i].words) == 0 && attach {
+			attached[i] = true
+			attach = !blankBefore[i]
+		} else {
+			attach = false
+		}
+	}
+
+	var out []string
+	inTest := false
+	for i, line := range lines {
+		header := line.isTest() || attached[i]
+		first := header && (i == 0 || !attached[i-1])
+		if blankBefore[i] || (first && i > 0) {
+			out = append(out, "")
+		}
+
+		prefix := strings.Repeat(indent, line.level())
+		if inTest && !header {
+			prefix = indent + prefix
+		}
+		if line.isTest() {
+			inTest = true
+		}
+		out = append(out, prefix+formatLine(line))
+	}
+
+	if len(out) == 0 {
+		return ""
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+func formatLine(line sourceLine) string {
+	var words []string
+	if line.isTest() {
+		words = []string{testPrefix}
+		if name := strings.TrimPrefix(strings.Join(line.words, " "), testPrefix); strings.TrimSpace(name) != "" {
+			words = append(words, strings.TrimSpace(name))
+		}
+	} else {
+		for i, word := range line.words {
+			// the name of a macro is not a command
+			if canonical, ok := aliases[word]; ok && !(i > 0 && (line.words[i-1] == "macro" || line.words[i-1] == "disasm")) {
+				word = canonical
+			}
+			words = append(words, word)
+		}
+	}
+	if line.comment != "" {
+		words = append(words, line.comment)
+	}
+	return strings.Join(words, " ")
+}
+
+// blockWords -> split a line into words like tokenize, except that the { and } of a block are words of their own so
+// that the commands inside it are formatted like any others
+func blockWords(code string) []string {
+	var words []string
+	var word strings.Builder
+	vector := 0
+	quoted, escaped, space := false, false, false
+	next := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, c := range code {
+		switch {
+		case quoted:
+			word.WriteRune(c)
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				quoted = false
+			}
+		case unicode.IsSpace(c):
+			space = vector > 0
+			if vector == 0 {
+				next()
+			}
+		case vector == 0 && (c == '{' || c == '}'):
+			next()
+			words = append(words, string(c))
+		default:
+			if space {
+				word.WriteByte(' ')
+				space = false
+			}
+			switch c {
+			case '"':
+				quoted = true
+			case '[':
+				vector++
+			case ']':
+				if vector > 0 {
+					vector--
+				}
+			}
+			word.WriteRune(c)
+		}
+	}
+	next()
+	return words
+}
+
+// splitComment -> split a line at the first word that starts with #, outside of any string literal
+func splitComment(text string) (string, string) {
+	start := true
+	quoted, escaped := false, false
+	for i, c := range text {
+		if quoted {
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				quoted = false
+			}
+			continue
+		}
+		if c == '"' {
+			quoted = true
+		}
+		if c == ' ' || c == '\t' || c == '\r' {
+			start = true
+			continue
+		}
+		if c == '#' && start {
+			return text[:i], strings.TrimRight(text[i:], " \t\r")
+		}
+		start = false
+	}
+	return text, ""
+}
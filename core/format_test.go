@@ -0,0 +1,93 @@
+package core
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files with the output of Format")
+
+// goldenFiles -> the scripts in testdata, each with the file of its expected formatting
+func goldenFiles(t *testing.T) map[string]string {
+	t.Helper()
+	scripts, err := filepath.Glob(filepath.Join("testdata", "*.rpn"))
+	if err != nil || len(scripts) == 0 {
+		t.Fatalf("no scripts in testdata: %v", err)
+	}
+	files := make(map[string]string)
+	for _, script := range scripts {
+		files[script] = strings.TrimSuffix(script, ".rpn") + ".golden"
+	}
+	return files
+}
+
+func readFile(t *testing.T, file string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFormatGolden(t *testing.T) {
+	for script, golden := range goldenFiles(t) {
+		formatted := Format(readFile(t, script))
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(formatted), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if expected := readFile(t, golden); formatted != expected {
+			t.Errorf("%v:\n%v", script, unifiedDiff(golden, expected, formatted))
+		}
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	for script := range goldenFiles(t) {
+		once := Format(readFile(t, script))
+		if twice := Format(once); twice != once {
+			t.Errorf("%v is formatted differently the second time:\n%v", script, unifiedDiff(script, once, twice))
+		}
+	}
+}
+
+// outcomes -> the stack left by the setup of a script and by each of its tests
+func outcomes(file, src string) ([]string, error) {
+	setup, tests := splitTests(parseScript(file, src))
+	tests = append([]testCase{{}}, tests...)
+	shown := make([]string, len(tests))
+	for i, test := range tests {
+		c := New()
+		if err := c.runScript(append(append([]scriptLine(nil), setup...), test.lines...)); err != nil {
+			return nil, err
+		}
+		shown[i] = c.Show(list(c.Stack()))
+	}
+	return shown, nil
+}
+
+// TestFormatMeaning -> formatting changes the layout of a script but not what it does
+func TestFormatMeaning(t *testing.T) {
+	for script := range goldenFiles(t) {
+		src := readFile(t, script)
+		before, err := outcomes(script, src)
+		if err != nil {
+			t.Errorf("%v before formatting: %v", script, err)
+			continue
+		}
+		after, err := outcomes(script, Format(src))
+		if err != nil {
+			t.Errorf("%v after formatting: %v", script, err)
+			continue
+		}
+		if strings.Join(before, "\n") != strings.Join(after, "\n") {
+			t.Errorf("%v: got %v before formatting and %v after", script, before, after)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// bases -> the base integers are read and shown in for each input mode
+var bases = map[string]int{DEC: 10, DECIMAL: 10, SIGFIG: 10, HEX: 16, OCT: 8, BIN: 2}
+
+// integer -> a whole number of any size, read and shown in the base of the input mode
+type integer struct {
+	value *big.Int
+}
+
+func parseInteger(item, mode string) (*big.Int, bool) {
+	return new(big.Int).SetString(item, bases[mode])
+}
+
+func (c *Calculator) formatInteger(x *big.Int) string {
+	return x.Text(bases[c.mode])
+}
+
+func (x integer) Kind() Kind {
+	return Integer
+}
+
+func (x integer) show(c *Calculator) string {
+	return c.formatInteger(x.value)
+}
+
+func (x integer) Equal(other Value) bool {
+	return numericEqual(x, other)
+}
+
+// handleInteger -> run a command exactly when its operands are all integers, returning false when it
+// isn't an integer operation so that the operands are promoted to floats instead
+func (c *Calculator) handleInteger(token Token) bool {
+	switch token.Type {
+	case PLUS:
+		op1 := c.popInteger(PLUS)
+		op2 := c.popInteger(PLUS)
+		c.pushInteger(new(big.Int).Add(op2, op1))
+	case MINUS:
+		op1 := c.popInteger(MINUS)
+		op2 := c.popInteger(MINUS)
+		c.pushInteger(new(big.Int).Sub(op2, op1))
+	case MULTIPLY:
+		op1 := c.popInteger(MULTIPLY)
+		op2 := c.popInteger(MULTIPLY)
+		c.pushInteger(new(big.Int).Mul(op2, op1))
+	case MOD:
+		op1 := c.popInteger(MOD)
+		op2 := c.popInteger(MOD)
+		if op1.Sign() == 0 {
+			throwDivisionByZeroError(MOD)
+		}
+		c.pushInteger(new(big.Int).Rem(op2, op1))
+	case DIVINT:
+		op1 := c.popInteger(DIVINT)
+		op2 := c.popInteger(DIVINT)
+		if op1.Sign() == 0 {
+			throwDivisionByZeroError(DIVINT)
+		}
+		c.pushInteger(new(big.Int).Quo(op2, op1))
+	case POW:
+		// negative powers aren't integers
+		if c.top().Sign() < 0 {
+			return false
+		}
+		op1 := c.popInteger(POW)
+		op2 := c.popInteger(POW)
+		c.checkBits(float64(op2.BitLen()) * integerToFloat(op1))
+		c.pushInteger(new(big.Int).Exp(op2, op1, nil))
+	case FACT:
+		if !c.top().IsInt64() {
+			return false
+		}
+		op1 := c.popInteger(FACT)
+		if op1.Sign() <= 0 {
+			c.pushInteger(big.NewInt(1))
+		} else {
+			n := float64(op1.Int64())
+			c.checkBits(n * math.Log2(n))
+			c.pushInteger(new(big.Int).MulRange(1, op1.Int64()))
+		}
+	case DECR:
+		op1 := c.popInteger(DECR)
+		c.pushInteger(new(big.Int).Sub(op1, big.NewInt(1)))
+	case INCR:
+		op1 := c.popInteger(INCR)
+		c.pushInteger(new(big.Int).Add(op1, big.NewInt(1)))
+
+	case BITAND:
+		op1 := c.popInteger(BITAND)
+		op2 := c.popInteger(BITAND)
+		c.pushInteger(new(big.Int).And(op2, op1))
+	case BITOR:
+		op1 := c.popInteger(BITOR)
+		op2 := c.popInteger(BITOR)
+		c.pushInteger(new(big.Int).Or(op2, op1))
+	case BITXOR:
+		op1 := c.popInteger(BITXOR)
+		op2 := c.popInteger(BITXOR)
+		c.pushInteger(new(big.Int).Xor(op2, op1))
+	case BITNOT:
+		op1 := c.popInteger(BITNOT)
+		op2 := c.popInteger(BITNOT)
+		c.pushInteger(new(big.Int).AndNot(op2, op1))
+	case BITLEFT:
+		c.checkShift(BITLEFT)
+		op1 := c.popInteger(BITLEFT)
+		op2 := c.popInteger(BITLEFT)
+		c.pushInteger(c.shift(op2, op1.Int64()))
+	case BITRIGHT:
+		c.checkShift(BITRIGHT)
+		op1 := c.popInteger(BITRIGHT)
+		op2 := c.popInteger(BITRIGHT)
+		c.pushInteger(c.shift(op2, -op1.Int64()))
+
+	case LT:
+		op1 := c.popInteger(LT)
+		op2 := c.popInteger(LT)
+		c.push(boolean(op2.Cmp(op1) < 0))
+	case LTOREQ:
+		op1 := c.popInteger(LTOREQ)
+		op2 := c.popInteger(LTOREQ)
+		c.push(boolean(op2.Cmp(op1) <= 0))
+	case NOTEQ:
+		op1 := c.popInteger(NOTEQ)
+		op2 := c.popInteger(NOTEQ)
+		c.push(boolean(op2.Cmp(op1) != 0))
+	case EQ:
+		op1 := c.popInteger(EQ)
+		op2 := c.popInteger(EQ)
+		c.push(boolean(op2.Cmp(op1) == 0))
+	case GT:
+		op1 := c.popInteger(GT)
+		op2 := c.popInteger(GT)
+		c.push(boolean(op2.Cmp(op1) > 0))
+	case GTOREQ:
+		op1 := c.popInteger(GTOREQ)
+		op2 := c.popInteger(GTOREQ)
+		c.push(boolean(op2.Cmp(op1) >= 0))
+
+	case CEIL, FLOOR, ROUND, IP:
+		c.pushInteger(c.popInteger(token.Type))
+	case FP:
+		c.popInteger(FP)
+		c.pushInteger(new(big.Int))
+	case SIGN:
+		op1 := c.popInteger(SIGN)
+		c.pushInteger(big.NewInt(int64(op1.Sign())))
+	case ABS:
+		c.pushInteger(new(big.Int).Abs(c.popInteger(ABS)))
+	case MAX:
+		op1 := c.popInteger(MAX)
+		op2 := c.popInteger(MAX)
+		if op2.Cmp(op1) > 0 {
+			op1 = op2
+		}
+		c.pushInteger(op1)
+	case MIN:
+		op1 := c.popInteger(MIN)
+		op2 := c.popInteger(MIN)
+		if op2.Cmp(op1) < 0 {
+			op1 = op2
+		}
+		c.pushInteger(op1)
+	default:
+		return false
+	}
+	return true
+}
+
+// operandsAre -> whether the operands of a command on top of the stack all have one of the given types
+func (c *Calculator) operandsAre(command string, kinds ...Kind) bool {
+	effect, ok := effects[command]
+	arity := len(effect.in)
+	if !ok || arity == 0 || len(c.stack) < arity {
+		return false
+	}
+	for _, item := range c.stack[len(c.stack)-arity:] {
+		if !hasKind(kinds, item.Kind()) {
+			return false
+		}
+	}
+	return true
+}
+
+// top -> the integer on top of the stack, for commands that only work exactly on some integers
+func (c *Calculator) top() *big.Int {
+	return c.stack[len(c.stack)-1].(integer).value
+}
+
+func hasKind(kinds []Kind, kind Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// power -> x to the power n by repeated squaring, stopping when the context is done or the result would have too many
+// bits
+func (c *Calculator) power(x *big.Int, n uint64) *big.Int {
+	result := big.NewInt(1)
+	square := new(big.Int).Set(x)
+	for ; n > 0; n >>= 1 {
+		c.checkDone()
+		if n&1 == 1 {
+			c.checkBits(float64(result.BitLen() + square.BitLen()))
+			result.Mul(result, square)
+		}
+		if n > 1 {
+			c.checkBits(float64(2 * square.BitLen()))
+			square.Mul(square, square)
+		}
+	}
+	return result
+}
+
+// checkShift -> check that the number of bits on top of the stack is one a shift can be made by, as shiftBits does
+// for floats
+func (c *Calculator) checkShift(command string) {
+	if c.top().Sign() < 0 {
+		throw("Cannot shift by a negative number of bits: %v", command)
+	}
+	if !c.top().IsInt64() {
+		throw("Shift out of range: %v", c.top())
+	}
+}
+
+// shift -> x shifted left by n bits, or right if n is negative. The size of the result is checked before it is made,
+// since a big enough shift runs out of memory.
+func (c *Calculator) shift(x *big.Int, n int64) *big.Int {
+	if n < 0 {
+		return new(big.Int).Rsh(x, uint(-n))
+	}
+	c.checkBits(float64(x.BitLen()) + float64(n))
+	return new(big.Int).Lsh(x, uint(n))
+}
+
+func (c *Calculator) pushInteger(x *big.Int) {
+	c.checkBits(float64(x.BitLen()))
+	c.push(integer{x})
+}
+
+func (c *Calculator) popInteger(command string) *big.Int {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	x, ok := item.(integer)
+	if !ok {
+		throwWrongElementType(Integer, item.Kind())
+	}
+	return x.value
+}
+
+func integerToFloat(x *big.Int) float64 {
+	number, _ := new(big.Float).SetInt(x).Float64()
+	return number
+}
+
+func throwDivisionByZeroError(action string) {
+	throw("Division by zero: %v", action)
+}
+
+func invalidNumber(item string) error {
+	return fmt.Errorf("Invalid number: %v", item)
+}
@@ -0,0 +1,28 @@
+package core
+
+import "testing"
+
+func TestInteger(t *testing.T) {
+	testResults(t, []resultTest{
+		{"2 64 pow 1 +", "18446744073709551617"},
+		{"2 64 pow 2 64 pow 1 - -", "1"},
+		{"25 fact", "15511210043330985984000000"},
+		{"30 fact", "265252859812191058636308480000000"},
+		{"2 100 pow 3 %", "1"},
+		{"2 100 pow 7 div", "181092942889747057356671886482"},
+		{"-7 2 div", "-3"},
+		{"-7 2 %", "-1"},
+		{"5 3 &", "1"},
+		{"5 3 |", "7"},
+		{"5 3 ^", "6"},
+		{"1 70 <<", "1180591620717411303424"},
+		{"2 70 pow 65 >>", "32"},
+		// operations that aren't exact on integers give floats
+		{"2 0.5 pow", "1.4142135623730951"},
+		{"2 64 pow 1.5 *", "27670116110564327000"},
+		{"7 0 div", "error: Division by zero: div"},
+		{"7 0 %", "error: Division by zero: %"},
+		{"1 -1 <<", "error: Cannot shift by a negative number of bits: <<"},
+		{"1 -1 >>", "error: Cannot shift by a negative number of bits: >>"},
+	})
+}
@@ -0,0 +1,386 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// interval -> a range of numbers guaranteed to contain the true value
+type interval struct {
+	lo, hi float64
+}
+
+// parseInterval -> parse an interval literal written as [1.9,2.1] or 2±0.1, rounding the ends outwards
+func parseInterval(item, mode string, digits int) (Value, bool) {
+	if mode != DEC {
+		return nil, false
+	}
+	var lo, hi *big.Rat
+	if strings.HasPrefix(item, "[") && strings.HasSuffix(item, "]") {
+		parts := strings.Split(item[1:len(item)-1], ",")
+		if len(parts) != 2 {
+			return nil, false
+		}
+		lo, hi = decimalRat(strings.TrimSpace(parts[0])), decimalRat(strings.TrimSpace(parts[1]))
+		if lo == nil || hi == nil || lo.Cmp(hi) > 0 {
+			return nil, false
+		}
+	} else if parts := strings.Split(item, "±"); len(parts) == 2 {
+		centre, radius := decimalRat(parts[0]), decimalRat(parts[1])
+		if centre == nil || radius == nil || radius.Sign() < 0 {
+			return nil, false
+		}
+		lo = new(big.Rat).Sub(centre, radius)
+		hi = new(big.Rat).Add(centre, radius)
+	} else {
+		return nil, false
+	}
+	return interval{roundRat(lo, big.ToNegativeInf), roundRat(hi, big.ToPositiveInf)}, true
+}
+
+func decimalRat(item string) *big.Rat {
+	if _, err := strconv.ParseFloat(item, 64); err != nil {
+		return nil
+	}
+	x, ok := new(big.Rat).SetString(item)
+	if !ok {
+		return nil
+	}
+	return x
+}
+
+func (x interval) Kind() Kind {
+	return Interval
+}
+
+func (x interval) show(c *Calculator) string {
+	return x.String()
+}
+
+// String -> an interval as its bounds in brackets, which is how it is shown in every mode
+func (x interval) String() string {
+	return "[" + strconv.FormatFloat(x.lo, 'g', -1, 64) + "," + strconv.FormatFloat(x.hi, 'g', -1, 64) + "]"
+}
+
+func (x interval) Equal(other Value) bool {
+	y, ok := other.(interval)
+	return ok && x == y
+}
+
+// handleInterval -> run a command on intervals when one of its operands is an interval
+func (c *Calculator) handleInterval(token Token) bool {
+	switch token.Type {
+	case LO:
+		c.push(float(c.popInterval(LO).lo))
+	case HI:
+		c.push(float(c.popInterval(HI).hi))
+	case MID:
+		op1 := c.popInterval(MID)
+		c.push(float(op1.lo/2 + op1.hi/2))
+	case WIDTH:
+		op1 := c.popInterval(WIDTH)
+		c.push(float(roundOp(op1.hi, op1.lo, MINUS, big.ToPositiveInf)))
+
+	case PLUS:
+		op1 := c.popInterval(PLUS)
+		op2 := c.popInterval(PLUS)
+		c.pushInterval(interval{
+			roundOp(op2.lo, op1.lo, PLUS, big.ToNegativeInf),
+			roundOp(op2.hi, op1.hi, PLUS, big.ToPositiveInf),
+		})
+	case MINUS:
+		op1 := c.popInterval(MINUS)
+		op2 := c.popInterval(MINUS)
+		c.pushInterval(interval{
+			roundOp(op2.lo, op1.hi, MINUS, big.ToNegativeInf),
+			roundOp(op2.hi, op1.lo, MINUS, big.ToPositiveInf),
+		})
+	case MULTIPLY:
+		op1 := c.popInterval(MULTIPLY)
+		op2 := c.popInterval(MULTIPLY)
+		c.pushInterval(corners(op2, op1, MULTIPLY))
+	case DIVIDE:
+		op1 := c.popInterval(DIVIDE)
+		op2 := c.popInterval(DIVIDE)
+		if op1.lo <= 0 && op1.hi >= 0 {
+			// dividing by an interval containing zero can give anything
+			c.pushInterval(interval{math.Inf(-1), math.Inf(1)})
+		} else {
+			c.pushInterval(corners(op2, op1, DIVIDE))
+		}
+	case POW:
+		op1 := c.popInterval(POW)
+		op2 := c.popInterval(POW)
+		c.pushInterval(c.intervalPow(op2, op1))
+	case DECR:
+		op1 := c.popInterval(DECR)
+		c.pushInterval(interval{roundOp(op1.lo, 1, MINUS, big.ToNegativeInf), roundOp(op1.hi, 1, MINUS, big.ToPositiveInf)})
+	case INCR:
+		op1 := c.popInterval(INCR)
+		c.pushInterval(interval{roundOp(op1.lo, 1, PLUS, big.ToNegativeInf), roundOp(op1.hi, 1, PLUS, big.ToPositiveInf)})
+
+	case LT, LTOREQ, GT, GTOREQ, EQ, NOTEQ:
+		op1 := c.popInterval(token.Type)
+		op2 := c.popInterval(token.Type)
+		c.push(compareIntervals(token.Type, op2, op1))
+
+	case SQRT:
+		op1 := domain(c.popInterval(SQRT), 0, SQRT)
+		c.pushInterval(interval{roundSqrt(op1.lo, big.ToNegativeInf), roundSqrt(op1.hi, big.ToPositiveInf)})
+	case EXP:
+		c.pushInterval(increasing(c.popInterval(EXP), math.Exp))
+	case LN:
+		c.pushInterval(increasing(domain(c.popInterval(LN), 0, LN), math.Log))
+	case LOG:
+		c.pushInterval(increasing(domain(c.popInterval(LOG), 0, LOG), math.Log10))
+	case ASIN:
+		c.pushInterval(increasing(domain(c.popInterval(ASIN), -1, ASIN), math.Asin))
+	case ACOS:
+		op1 := domain(c.popInterval(ACOS), -1, ACOS)
+		c.pushInterval(outward(math.Acos(op1.hi), math.Acos(op1.lo)))
+	case ATAN:
+		c.pushInterval(increasing(c.popInterval(ATAN), math.Atan))
+	case SINH:
+		c.pushInterval(increasing(c.popInterval(SINH), math.Sinh))
+	case TANH:
+		c.pushInterval(increasing(c.popInterval(TANH), math.Tanh))
+	case COSH:
+		op1 := c.popInterval(COSH)
+		result := outward(math.Min(math.Cosh(op1.lo), math.Cosh(op1.hi)), math.Max(math.Cosh(op1.lo), math.Cosh(op1.hi)))
+		if op1.lo <= 0 && op1.hi >= 0 {
+			result.lo = 1
+		}
+		c.pushInterval(result)
+	case SIN:
+		// sin x = cos(x - pi/2)
+		c.pushInterval(periodic(c.popInterval(SIN), math.Sin, math.Pi/2, -math.Pi/2))
+	case COS:
+		c.pushInterval(periodic(c.popInterval(COS), math.Cos, 0, math.Pi))
+
+	case ABS:
+		op1 := c.popInterval(ABS)
+		switch {
+		case op1.lo >= 0:
+			c.pushInterval(op1)
+		case op1.hi <= 0:
+			c.pushInterval(interval{-op1.hi, -op1.lo})
+		default:
+			c.pushInterval(interval{0, math.Max(-op1.lo, op1.hi)})
+		}
+	case MAX:
+		op1 := c.popInterval(MAX)
+		op2 := c.popInterval(MAX)
+		c.pushInterval(interval{math.Max(op2.lo, op1.lo), math.Max(op2.hi, op1.hi)})
+	case MIN:
+		op1 := c.popInterval(MIN)
+		op2 := c.popInterval(MIN)
+		c.pushInterval(interval{math.Min(op2.lo, op1.lo), math.Min(op2.hi, op1.hi)})
+	default:
+		return false
+	}
+	return true
+}
+
+// compareIntervals -> true or false when every pair of values compares the same way, and unknown otherwise
+func compareIntervals(command string, a, b interval) Value {
+	var always, never bool
+	switch command {
+	case LT:
+		always, never = a.hi < b.lo, a.lo >= b.hi
+	case LTOREQ:
+		always, never = a.hi <= b.lo, a.lo > b.hi
+	case GT:
+		always, never = a.lo > b.hi, a.hi <= b.lo
+	case GTOREQ:
+		always, never = a.lo >= b.hi, a.hi < b.lo
+	case EQ:
+		always, never = a.lo == a.hi && a == b, a.hi < b.lo || b.hi < a.lo
+	case NOTEQ:
+		always, never = a.hi < b.lo || b.hi < a.lo, a.lo == a.hi && a == b
+	}
+	switch {
+	case always:
+		return boolean(true)
+	case never:
+		return boolean(false)
+	}
+	return unknown{}
+}
+
+// corners -> the smallest interval containing op applied to the ends of a and b, for * and /
+func corners(a, b interval, op string) interval {
+	result := interval{math.Inf(1), math.Inf(-1)}
+	for _, x := range []float64{a.lo, a.hi} {
+		for _, y := range []float64{b.lo, b.hi} {
+			result.lo = math.Min(result.lo, roundOp(x, y, op, big.ToNegativeInf))
+			result.hi = math.Max(result.hi, roundOp(x, y, op, big.ToPositiveInf))
+		}
+	}
+	return result
+}
+
+func (c *Calculator) intervalPow(base, exponent interval) interval {
+	if exponent.lo == exponent.hi && exponent.lo == math.Trunc(exponent.lo) && math.Abs(exponent.lo) < 1<<31 {
+		n := int64(exponent.lo)
+		magnitude := uint64(n)
+		if n < 0 {
+			magnitude = uint64(-n)
+		}
+		lo, hi := c.powerBounds(base.lo, magnitude), c.powerBounds(base.hi, magnitude)
+		var result interval
+		switch {
+		case n == 0:
+			result = interval{1, 1}
+		case base.lo >= 0:
+			result = interval{lo.lo, hi.hi}
+		case n%2 != 0:
+			// odd powers keep their order and sign
+			result = interval{-lo.hi, -hi.lo}
+			if base.hi > 0 {
+				result.hi = hi.hi
+			}
+		case base.hi <= 0:
+			result = interval{hi.lo, lo.hi}
+		default:
+			// even powers can't be negative
+			result = interval{0, math.Max(lo.hi, hi.hi)}
+		}
+		if n < 0 {
+			if result.lo <= 0 && result.hi >= 0 {
+				return interval{math.Inf(-1), math.Inf(1)}
+			}
+			result = corners(interval{1, 1}, result, DIVIDE)
+		}
+		return result
+	}
+	// x^y = e^(y ln x), which is only real for positive x
+	logs := increasing(domain(base, 0, POW), math.Log)
+	return increasing(corners(exponent, logs, MULTIPLY), math.Exp)
+}
+
+// powerBounds -> |x| to the power n by repeated squaring, rounded down for the low bound and up for the high one
+func (c *Calculator) powerBounds(x float64, n uint64) interval {
+	result, square := interval{1, 1}, interval{math.Abs(x), math.Abs(x)}
+	for ; n > 0; n >>= 1 {
+		c.checkDone()
+		if n&1 == 1 {
+			result = interval{roundOp(result.lo, square.lo, MULTIPLY, big.ToNegativeInf),
+				roundOp(result.hi, square.hi, MULTIPLY, big.ToPositiveInf)}
+		}
+		square = interval{roundOp(square.lo, square.lo, MULTIPLY, big.ToNegativeInf),
+			roundOp(square.hi, square.hi, MULTIPLY, big.ToPositiveInf)}
+	}
+	return result
+}
+
+// domain -> check that an interval is inside the domain of a function that is defined from min upwards,
+// or from min to -min when min is negative
+func domain(x interval, min float64, command string) interval {
+	if x.lo < min || (min < 0 && x.hi > -min) {
+		throw("Interval %v is outside the domain of %v", x, command)
+	}
+	return x
+}
+
+// increasing -> apply an increasing function to an interval
+func increasing(x interval, f func(float64) float64) interval {
+	return outward(f(x.lo), f(x.hi))
+}
+
+// periodic -> apply sin or cos to an interval, where max and min are where the function peaks and dips within its period
+func periodic(x interval, f func(float64) float64, max, min float64) interval {
+	if x.hi-x.lo >= 2*math.Pi {
+		return interval{-1, 1}
+	}
+	result := outward(math.Min(f(x.lo), f(x.hi)), math.Max(f(x.lo), f(x.hi)))
+	if containsPeriod(x, max) {
+		result.hi = 1
+	}
+	if containsPeriod(x, min) {
+		result.lo = -1
+	}
+	return interval{math.Max(result.lo, -1), math.Min(result.hi, 1)}
+}
+
+// containsPeriod -> whether the interval contains offset + 2kπ for some k, erring towards yes
+func containsPeriod(x interval, offset float64) bool {
+	k := math.Ceil((x.lo-offset)/(2*math.Pi) - 1e-9)
+	return offset+k*2*math.Pi <= x.hi+1e-9
+}
+
+// outward -> widen a result from the math package, which is accurate to within an ulp or so, to be sure it contains the true value
+func outward(lo, hi float64) interval {
+	for i := 0; i < 2; i++ {
+		lo, hi = math.Nextafter(lo, math.Inf(-1)), math.Nextafter(hi, math.Inf(1))
+	}
+	return interval{lo, hi}
+}
+
+// roundOp -> x op y rounded in the given direction
+func roundOp(x, y float64, op string, rounding big.RoundingMode) float64 {
+	if math.IsInf(x, 0) || math.IsInf(y, 0) || math.IsNaN(x) || math.IsNaN(y) {
+		switch op {
+		case PLUS:
+			return x + y
+		case MINUS:
+			return x - y
+		case MULTIPLY:
+			return x * y
+		default:
+			return x / y
+		}
+	}
+	a, b := big.NewFloat(x), big.NewFloat(y)
+	z := new(big.Float).SetPrec(53).SetMode(rounding)
+	switch op {
+	case PLUS:
+		z.Add(a, b)
+	case MINUS:
+		z.Sub(a, b)
+	case MULTIPLY:
+		z.Mul(a, b)
+	default:
+		z.Quo(a, b)
+	}
+	result, _ := z.Float64()
+	// the difference of equal numbers rounded down is -0, which adding zero turns into 0
+	return result + 0
+}
+
+func roundSqrt(x float64, rounding big.RoundingMode) float64 {
+	if math.IsInf(x, 0) {
+		return x
+	}
+	result, _ := new(big.Float).SetPrec(53).SetMode(rounding).Sqrt(big.NewFloat(x)).Float64()
+	return result
+}
+
+func roundRat(x *big.Rat, rounding big.RoundingMode) float64 {
+	result, _ := new(big.Float).SetPrec(53).SetMode(rounding).SetRat(x).Float64()
+	return result
+}
+
+func (c *Calculator) pushInterval(x interval) {
+	c.push(x)
+}
+
+// popInterval -> pop an interval, turning a number into the smallest interval that contains it
+func (c *Calculator) popInterval(command string) interval {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	if x, ok := item.(interval); ok {
+		return x
+	}
+	if x, ok := ratValue(item); ok {
+		return interval{roundRat(x, big.ToNegativeInf), roundRat(x, big.ToPositiveInf)}
+	}
+	number, ok := numberValue(item)
+	if !ok {
+		throwWrongElementType(Interval, item.Kind())
+	}
+	return interval{number, number}
+}
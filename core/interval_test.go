@@ -0,0 +1,32 @@
+package core
+
+import "testing"
+
+func TestInterval(t *testing.T) {
+	testResults(t, []resultTest{
+		{"[1,2] [3,4] +", "[4,6]"},
+		{"[1,2] 1 -", "[0,1]"},
+		{"[1,1] [1,1] -", "[0,0]"},
+		{"[1,2] [3,4] *", "[3,8]"},
+		{"[-1,2] dup *", "[-2,4]"},
+		{"[1,2] [1,4] /", "[0.25,2]"},
+		{"1 [-1,1] /", "[-Inf,+Inf]"},
+		{"[-2,3] 2 pow", "[0,9]"},
+		{"[-2,3] 3 pow", "[-8,27]"},
+		{"[1,4] sqrt", "[1,2]"},
+		{"[1,2] lo", "1"},
+		{"[1,2] hi", "2"},
+		{"[1,2] mid", "1.5"},
+		{"[1,2] width", "1"},
+
+		// bounds that can't be represented are rounded outwards
+		{"[0.1,0.1] [0.2,0.2] +", "[0.29999999999999993,0.30000000000000004]"},
+		{"[0,1] exp", "[0.9999999999999998,2.718281828459046]"},
+
+		// comparisons of intervals that overlap are unknown
+		{"[1,2] [3,4] <", "true"},
+		{"[3,4] [1,2] <", "false"},
+		{"[1,3] [2,4] <", "unknown"},
+		{"[1,2] [1,2] ==", "unknown"},
+	})
+}
@@ -2,6 +2,8 @@ package core
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
@@ -12,150 +14,216 @@ import (
 	"github.com/mitchellh/go-homedir"
 )
 
-var mode = DEC
-var display = "horizontal"
-var repl = false
-
-// Calculate -> run a calculation for a sequence of commands
-func Calculate(args []string) {
+// Calculate -> run a calculation for a sequence of commands, printing its result
+func (c *Calculator) Calculate(args []string) error {
+	// the shell splits [1.9 2.1] and "hello world" into two arguments
+	args = tokenize(strings.Join(args, " "))
 	var input []string
 	// check if there's anything in stdin (from a pipe perhaps)
 	stat, _ := os.Stdin.Stat()
 	if (stat.Mode() & os.ModeCharDevice) == 0 {
 		scanner := bufio.NewScanner(os.Stdin)
 		if scanned := scanner.Scan(); scanned {
-			input = strings.Split(scanner.Text(), " ")
+			input = tokenize(scanner.Text())
 		}
 	}
 
-	if config, err := getConfig(); err == nil {
-		args = append(strings.Split(config, " "), args...)
+	if config, err := c.getConfig(); err == nil {
+		args = append(tokenize(config), args...)
+	}
+
+	if err := protect(func() { c.eval(append(args, input...)) }); err != nil {
+		if err == ErrExit {
+			fmt.Println("Goodbye")
+			return nil
+		}
+		return err
+	}
+
+	if result, ok := c.result(); ok {
+		fmt.Fprintln(os.Stdout, result)
+	}
+	return nil
+}
+
+// result -> the result of a calculation as it is printed, which is the item at the bottom of the stack
+func (c *Calculator) result() (string, bool) {
+	if len(c.stack) == 0 {
+		return "", false
 	}
+	if s, ok := c.stack[0].(text); ok {
+		// a string result is printed as it is, so it can be used as a message
+		return string(s), true
+	}
+	return c.stack[0].show(c), true
+}
 
-	eval(append(args, input...))
+// CalculateBatch -> run the commands in args, then each line of stdin as a calculation of its own starting from
+// where they left off, on a number of goroutines, printing the result of each line in the order of the lines. A line
+// that fails or leaves nothing on the stack prints an empty line, so that the output lines up with the input.
+func (c *Calculator) CalculateBatch(args []string, jobs int) error {
+	args = tokenize(strings.Join(args, " "))
+	if config, err := c.getConfig(); err == nil {
+		args = append(tokenize(config), args...)
+	}
+	if err := protect(func() { c.eval(args) }); err != nil {
+		return err
+	}
 
-	if len(stack) > 0 {
-		result := stack[0]
-		fmt.Fprintln(os.Stdout, showResultValue(result.Literal))
+	var lines []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	failed := 0
+	for i, result := range NewPool(c, jobs).EvalBatch(context.Background(), lines) {
+		if result.Err != nil && result.Err != ErrExit {
+			fmt.Fprintf(os.Stderr, "rpn: line %v: %v\n", i+1, result.Err)
+			failed++
+		}
+		fmt.Fprintln(os.Stdout, result.Text)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v of %v lines failed", failed, len(lines))
 	}
+	return nil
 }
 
-// Repl -> create a read-eval-print loop
-func Repl() {
-	repl = true
+// Repl -> create a read-eval-print loop, which ends at the end of the input, at exit or at the first error
+func (c *Calculator) Repl() error {
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
-		printPrompt()
+		c.printPrompt()
 		if scanned := scanner.Scan(); !scanned {
-			return
+			return nil
 		}
-		text := strings.Split(scanner.Text(), " ")
-		if config, err := getConfig(); err == nil {
-			text = append(strings.Split(config, " "), text...)
+		text := tokenize(scanner.Text())
+		if config, err := c.getConfig(); err == nil {
+			text = append(tokenize(config), text...)
+		}
+		if err := protect(func() { c.eval(text) }); err != nil {
+			if err == ErrExit {
+				fmt.Println("Goodbye")
+				return nil
+			}
+			return err
 		}
-		eval(text)
 	}
 }
 
-func getConfig() (string, error) {
+// getConfig -> the commands in ~/.rpnrc, which are run before every calculation unless the calculator is sandboxed
+func (c *Calculator) getConfig() (string, error) {
+	if c.sandboxed {
+		return "", errors.New("No config in a sandbox")
+	}
 	home, err := homedir.Dir()
 	data, err := ioutil.ReadFile(fmt.Sprintf("%v/.rpnrc", home))
 	return string(data), err
 }
 
-func printPrompt() {
-	var valueStack []interface{}
-	for _, item := range stack {
-		valueStack = append(valueStack, item.Literal)
-	}
-	if display == "horizontal" {
-		printRegisterValues()
-		for _, item := range valueStack {
-			fmt.Printf("%v ", showResultValue(item))
+func (c *Calculator) printPrompt() {
+	if c.display == "horizontal" {
+		c.printRegisterValues()
+		for _, item := range c.stack {
+			fmt.Printf("%v ", item.show(c))
 		}
 		fmt.Print("> ")
 	} else {
 		fmt.Println("STACK TOP")
-		for i := len(valueStack) - 1; i >= 0; i-- {
-			fmt.Printf("%v\n", showResultValue(valueStack[i]))
+		for i := len(c.stack) - 1; i >= 0; i-- {
+			if rows, ok := c.stack[i].(matrix); ok {
+				fmt.Println(c.formatMatrixLines(rows))
+			} else {
+				fmt.Printf("%v\n", c.stack[i].show(c))
+			}
 		}
 		fmt.Println("STACK BOTTOM")
-		printRegisterValues()
+		c.printRegisterValues()
 		fmt.Print("> ")
 	}
 }
 
-func printRegisterValues() {
-	if len(values) > 0 {
+func (c *Calculator) printRegisterValues() {
+	if len(c.values) > 0 {
 		fmt.Print("[")
 	}
-	for i, v := range values {
-		fmt.Printf("%v= %v", i, showResultValue(v.Literal))
+	for i, v := range c.values {
+		fmt.Printf("%v= %v", i, v.show(c))
 	}
-	if len(values) > 0 {
+	if len(c.values) > 0 {
 		fmt.Print("] ")
 	}
 }
 
-func showResultValue(result interface{}) interface{} {
-	if _, ok := result.(bool); ok {
-		return result
-	}
-	switch mode {
-	case DEC:
-		return strconv.FormatFloat(result.(float64), 'f', -1, 64)
-	case BIN:
-		return getBinary(result.(float64))
-	case OCT:
-		return getOctal(result.(float64))
-	case HEX:
-		return getHex(result.(float64))
+// getInput -> parse a number in the current mode and precision
+func (c *Calculator) getInput(item string) (Value, error) {
+	return parseValue(item, c.mode, c.precision)
+}
+
+// parseValue -> parse a value in the given mode, at the given precision if it isn't 0
+func parseValue(item, mode string, digits int) (Value, error) {
+	for _, parse := range parsers {
+		if x, ok := parse(item, mode, digits); ok {
+			return x, nil
+		}
 	}
-	return result
+	return nil, invalidNumber(item)
 }
 
-func getInput(item string) (float64, error) {
+func parseNumber(item string, mode string) (float64, error) {
 	switch mode {
-	case BIN:
-		result, err := strconv.ParseInt(item, 2, 64)
-		if err != nil {
-			return 0, err
-		}
-		return float64(result), err
-	case OCT:
-		result, err := strconv.ParseInt(item, 8, 64)
-		if err != nil {
-			return 0, err
+	case BIN, OCT, HEX:
+		result, ok := parseInteger(item, mode)
+		if !ok {
+			return 0, invalidNumber(item)
 		}
-		return float64(result), err
-	case HEX:
-		result, err := strconv.ParseInt(item, 16, 64)
-		if err != nil {
-			return 0, err
-		}
-		return float64(result), err
+		return integerToFloat(result), nil
 	default:
 		return strconv.ParseFloat(item, 64)
 	}
 }
 
+// calcError is raised by the throw functions and recovered by protect
+type calcError struct {
+	err error
+}
+
+func throw(format string, a ...interface{}) {
+	panic(calcError{fmt.Errorf(format, a...)})
+}
+
+// protect runs f and returns the error thrown by it, if any
+func protect(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(calcError)
+			if !ok {
+				panic(r)
+			}
+			err = e.err
+		}
+	}()
+	f()
+	return nil
+}
+
 func throwNotEnoughElementsError(action string) {
-	fmt.Fprintf(os.Stderr, "rpn: Not enough items on the stack to perform this command: %v\n", action)
-	exit()
+	throw("Not enough items on the stack to perform this command: %v", action)
 }
 
 func throwNotEnoughArgumentsError(action string) {
-	fmt.Fprintf(os.Stderr, "rpn: Not enough arguments to perform this command: %v\n", action)
-	exit()
+	throw("Not enough arguments to perform this command: %v", action)
 }
 
-func throwWrongElementType(expected, actual string) {
-	fmt.Fprintf(os.Stderr, "rpn: Expected a %v on the stack but found a %v\n", expected, actual)
-	exit()
+func throwWrongElementType(expected, actual Kind) {
+	throw("Expected %v on the stack but found %v", expected.article(), actual.article())
 }
 
-func exit() {
-	os.Exit(1)
+func throwAssertionError(format string, a ...interface{}) {
+	throw("Assertion failed: "+format, a...)
 }
 
 func getBinary(num float64) string {
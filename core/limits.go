@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Limits -> bounds on a calculation, for running calculations that can't be trusted to finish. A limit of 0 is no
+// limit.
+type Limits struct {
+	// Steps -> the number of commands run, counting each command of a macro or a repeat every time it runs
+	Steps int
+	// Depth -> the number of items on the stack
+	Depth int
+	// Size -> the number of elements in a vector, matrix, polynomial or list, counting those of the lists in it, and
+	// the number of bytes in a string. Commands such as identity and * fail before they make an item that would be
+	// too large.
+	Size int
+	// Recursion -> the number of macro calls in progress at once
+	Recursion int
+	// Bits -> the size of an integer, fraction, decimal or arbitrary precision number, and of the digits asked for by
+	// prec and scale. Commands such as pow and fact fail before they start on a number that would be too large.
+	Bits int
+	// Timeout -> how long the calculation can run for. Commands that take long on large items, such as * on matrices,
+	// stop part way through, and other commands run to the end once they have started.
+	Timeout time.Duration
+}
+
+// LimitError -> the error a calculation fails with when it exceeds one of its limits
+type LimitError struct {
+	// Limit -> what was limited, as it is described in the message
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("Exceeded the limit of %v %v", e.Max, e.Limit)
+}
+
+func throwLimitError(limit string, max int) {
+	panic(calcError{&LimitError{Limit: limit, Max: max}})
+}
+
+// EvalContext -> run a line of commands within limits, stopping when the context is done. A calculation that is
+// stopped fails with a *LimitError or the error of the context, and leaves the calculator as it was, like Eval.
+func (c *Calculator) EvalContext(ctx context.Context, line string, limits Limits) error {
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+	c.ctx, c.limits, c.steps = ctx, &limits, 0
+	defer func() {
+		c.ctx, c.limits = nil, nil
+	}()
+	return c.Eval(line)
+}
+
+// step -> count a command towards the limits of the calculation, stopping it if they are exceeded or its context is
+// done
+func (c *Calculator) step() {
+	if c.limits == nil {
+		return
+	}
+	c.steps++
+	if c.limits.Steps > 0 && c.steps > c.limits.Steps {
+		throwLimitError("steps", c.limits.Steps)
+	}
+	c.checkDone()
+}
+
+// checkDone -> stop a calculation when its context is done, part way through a command that can take long
+func (c *Calculator) checkDone() {
+	if c.ctx == nil {
+		return
+	}
+	select {
+	case <-c.ctx.Done():
+		panic(calcError{c.ctx.Err()})
+	default:
+	}
+}
+
+// checkDepth -> stop a calculation before it has more items on the stack or in a list than its limit
+func (c *Calculator) checkDepth(n int) {
+	if c.limits != nil && c.limits.Depth > 0 && n > c.limits.Depth {
+		throwLimitError("items", c.limits.Depth)
+	}
+}
+
+// checkSize -> stop a calculation before it makes an item with more elements than its limit
+func (c *Calculator) checkSize(n float64) {
+	if c.limits != nil && c.limits.Size > 0 && n > float64(c.limits.Size) {
+		throwLimitError("elements in an item", c.limits.Size)
+	}
+}
+
+// sizeOf -> the number of elements in an item as Size counts them, counting no further than max
+func sizeOf(item Value, max int) int {
+	switch x := item.(type) {
+	case text:
+		return len(x)
+	case vector:
+		return len(x)
+	case polynomial:
+		return len(x)
+	case matrix:
+		if len(x) == 0 {
+			return 0
+		}
+		return len(x) * len(x[0])
+	case list:
+		n := len(x)
+		for i := 0; i < len(x) && n <= max; i++ {
+			n += sizeOf(x[i], max-n)
+		}
+		return n
+	}
+	return 0
+}
+
+// checkRecursion -> stop a calculation before it has more macro calls in progress than its limit
+func (c *Calculator) checkRecursion() {
+	if c.limits != nil && c.limits.Recursion > 0 && c.calls > c.limits.Recursion {
+		throwLimitError("nested macro calls", c.limits.Recursion)
+	}
+}
+
+// checkBits -> stop a calculation before it makes a number with more bits than its limit
+func (c *Calculator) checkBits(bits float64) {
+	if c.limits != nil && c.limits.Bits > 0 && bits > float64(c.limits.Bits) {
+		throwLimitError("bits in a number", c.limits.Bits)
+	}
+}
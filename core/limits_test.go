@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		line   string
+		limits Limits
+		// limit -> the limit the line exceeds, or "" if it runs within them
+		limit string
+	}{
+		{"1 2 +", Limits{Steps: 3}, ""},
+		{"0 10 repeat ++", Limits{Steps: 5}, "steps"},
+		{"1 2 3", Limits{Depth: 3}, ""},
+		{"1 2 3 4", Limits{Depth: 3}, "items"},
+		{"0 5 range", Limits{Size: 5}, ""},
+		{"0 6 range", Limits{Size: 5}, "elements in an item"},
+		{"100 identity", Limits{Size: 1000}, "elements in an item"},
+		{`"ab" "%100000v" format`, Limits{Size: 1000}, "elements in an item"},
+		{"2 10 pow", Limits{Bits: 64}, ""},
+		{"2 100 pow", Limits{Bits: 64}, "bits in a number"},
+		{"100 fact", Limits{Bits: 64}, "bits in a number"},
+		{"1000 prec", Limits{Bits: 64}, "bits in a number"},
+		{"1 100000000000 <<", Limits{Bits: 4096}, "bits in a number"},
+		{"decimal 1.1 100000000 pow", Limits{Bits: 4096}, "bits in a number"},
+		{"decimal 1000000000 scale", Limits{Bits: 4096}, "bits in a number"},
+		{"decimal 1.1 10 pow", Limits{Bits: 4096}, ""},
+		{"decimal 1.1 10000 pow", Limits{Timeout: time.Second}, ""},
+		{"[1,2] 1000000000 pow", Limits{Timeout: time.Second}, ""},
+	}
+	for _, test := range tests {
+		c := New()
+		err := c.EvalContext(context.Background(), test.line, test.limits)
+		var limit *LimitError
+		switch {
+		case test.limit == "" && err != nil:
+			t.Errorf("%q: %v", test.line, err)
+		case test.limit != "" && (!errors.As(err, &limit) || limit.Limit != test.limit):
+			t.Errorf("%q: expected to exceed the limit of %v, got %v", test.line, test.limit, err)
+		case test.limit != "" && len(c.Stack()) != 0:
+			t.Errorf("%q: left %v on the stack", test.line, c.Stack())
+		}
+	}
+}
+
+// TestLimitBypasses -> commands that used to run out of memory or time whatever the limits were
+func TestLimitBypasses(t *testing.T) {
+	lines := []string{"1 -100000000000 >>", "decimal 1.1 100000000 pow", "decimal 1000000000 scale 1 3 /"}
+	for _, line := range lines {
+		c := New()
+		if err := c.EvalContext(context.Background(), line, Limits{Bits: 4096, Timeout: time.Second}); err == nil {
+			t.Errorf("%q: expected an error, got %v", line, c.Stack())
+		}
+	}
+}
+
+func TestLimitRecursion(t *testing.T) {
+	c := New()
+	// inlining would take the calls out of the chain
+	c.DisableOptimizations()
+	for _, line := range []string{"macro m1 1 +", "macro m2 m1", "macro m3 m2", "macro m4 m3", "macro loop loop"} {
+		if err := c.Eval(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.EvalContext(context.Background(), "1 m4", Limits{Recursion: 4}); err != nil {
+		t.Errorf("1 m4: %v", err)
+	}
+	var limit *LimitError
+	for _, line := range []string{"1 m4", "loop"} {
+		if err := c.EvalContext(context.Background(), line, Limits{Recursion: 3}); !errors.As(err, &limit) {
+			t.Errorf("%q: expected to exceed the limit of nested macro calls, got %v", line, err)
+		}
+	}
+}
+
+func TestLimitTimeout(t *testing.T) {
+	lines := []string{
+		"0 1000000000 repeat ++",
+		"decimal 1.1 100000000 pow",
+		"200 identity dup * dup * dup *",
+	}
+	for _, line := range lines {
+		start := time.Now()
+		err := New().EvalContext(context.Background(), line, Limits{Timeout: 50 * time.Millisecond})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%q: expected the deadline to be exceeded, got %v", line, err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%q: took %v to stop", line, elapsed)
+		}
+	}
+}
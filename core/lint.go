@@ -0,0 +1,505 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxRepeat -> repeat counts above this are not simulated one by one
+const maxRepeat = 1000
+
+type problem struct {
+	pos position
+	msg string
+}
+
+// item -> what the checker knows about a stack item before the script runs
+type item struct {
+	typ   Kind
+	value float64
+	known bool
+	input int // 1-based index into the inputs of the macro being inferred, 0 if none
+}
+
+// wordEffect -> the inferred stack effect of a macro
+type wordEffect struct {
+	in      []Kind // top of the stack first
+	out     []item
+	mode    string
+	dynamic bool // the effect depends on values only known when the macro runs
+	exits   bool
+}
+
+// checker -> simulates a script on a stack of types instead of values
+type checker struct {
+	// calc -> the calculator whose commands the script is checked against
+	calc      *Calculator
+	pos       position
+	mode      string
+	stack     []item
+	open      bool // there are unknown items below the stack, so underflows can't be detected
+	inferring bool // items popped from below the stack are inputs of the macro being inferred
+	inputs    []Kind
+	dynamic   bool
+	exited    bool
+	reported  bool
+	macros    map[string]*wordEffect
+	registers map[string]Kind
+	problems  *[]problem
+}
+
+// Lint -> check the rpn scripts at the given paths for mistakes without running them
+func Lint(paths []string) (bool, error) {
+	return New().Lint(paths)
+}
+
+// Lint -> check scripts against the commands of this calculator, which may have commands of its own or lack some
+// of the built-in ones
+func (c *Calculator) Lint(paths []string) (bool, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var files []string
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && (file == path || strings.HasSuffix(file, ".rpn")) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	ok := true
+	for _, file := range files {
+		lines, err := loadScript(file)
+		if err != nil {
+			return false, err
+		}
+		for _, p := range c.checkScript(lines) {
+			fmt.Printf("%v: %v\n", p.pos, p.msg)
+			ok = false
+		}
+	}
+	return ok, nil
+}
+
+// checkScript -> check a script, running each test: block from the state left by the lines before the first one
+func (c *Calculator) checkScript(lines []scriptLine) []problem {
+	problems := make([]problem, 0)
+	state := &checker{
+		calc:      c,
+		mode:      DEC,
+		macros:    make(map[string]*wordEffect),
+		registers: make(map[string]Kind),
+		problems:  &problems,
+	}
+
+	var setup *checker
+	for _, line := range lines {
+		state.pos = line.pos
+		if strings.HasPrefix(line.words[0], testPrefix) {
+			if setup == nil {
+				setup = state.copy()
+			}
+			state = setup.copy()
+			continue
+		}
+		state.line(line.words)
+	}
+	return problems
+}
+
+func (c *checker) copy() *checker {
+	clone := *c
+	clone.stack = append([]item(nil), c.stack...)
+	clone.macros = make(map[string]*wordEffect)
+	for name, effect := range c.macros {
+		clone.macros[name] = effect
+	}
+	clone.registers = make(map[string]Kind)
+	for name, typ := range c.registers {
+		clone.registers[name] = typ
+	}
+	return &clone
+}
+
+func (c *checker) report(format string, a ...interface{}) {
+	*c.problems = append(*c.problems, problem{c.pos, fmt.Sprintf(format, a...)})
+}
+
+// line -> check a sequence of commands the way eval runs them
+func (c *checker) line(words []string) {
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if c.exited {
+			if !c.reported {
+				c.report("Unreachable code after exit: %v", word)
+				c.reported = true
+			}
+			return
+		}
+
+		token, ok := c.calc.keyword(word)
+		if !ok {
+			c.word(word)
+			continue
+		}
+		switch token.Type {
+		case REPEAT:
+			n := c.pop(word, Number)
+			if i+1 >= len(words) {
+				c.report("Not enough arguments to perform this command: %v", word)
+				return
+			}
+			c.repeat(n, words[i+1])
+			i++
+		case MACRODEF:
+			if len(words)-i < 3 {
+				c.report("Not enough arguments to perform this command: %v", word)
+				return
+			}
+			c.define(words[i+1], words[i+2:])
+			return
+		case DISASM:
+			if i+1 >= len(words) {
+				c.report("Not enough arguments to perform this command: %v", word)
+				return
+			}
+			if _, ok := c.macros[words[i+1]]; !ok {
+				c.report("Unknown macro: %v", words[i+1])
+			}
+			i++
+		default:
+			c.command(word, token)
+		}
+	}
+}
+
+// word -> check a number, register or macro
+func (c *checker) word(word string) {
+	if value, err := parseValue(word, c.mode, 0); err == nil {
+		switch value.Kind() {
+		case String, List, Vector, Matrix, Polynomial, Complex:
+			c.push(item{typ: value.Kind()})
+			return
+		}
+		number, _ := numberValue(value)
+		c.push(item{typ: Number, value: number, known: true})
+		return
+	}
+	if typ, ok := c.registers[word]; ok {
+		c.push(item{typ: typ})
+		return
+	}
+	if effect, ok := c.macros[word]; ok {
+		c.apply(word, effect)
+		return
+	}
+	c.report("Unknown command: %v", word)
+	c.unknown()
+}
+
+func (c *checker) command(word string, token Token) {
+	if op := c.calc.operators[token.Type]; op.custom {
+		for i := len(op.args) - 1; i >= 0; i-- {
+			c.pop(word, op.args[i])
+		}
+		// what a registered command pushes is only known once it runs
+		c.unknown()
+		return
+	}
+	switch token.Type {
+	case HEX, DEC, BIN, OCT, DECIMAL, SIGFIG:
+		c.mode = token.Type
+	case CLRSTACK, CLRALL:
+		if c.inferring {
+			c.unknown()
+		} else {
+			c.stack = nil
+			c.open = false
+		}
+		if token.Type == CLRALL {
+			c.registers = make(map[string]Kind)
+		}
+	case CLRVARS:
+		c.registers = make(map[string]Kind)
+	case DUP:
+		top := c.pop(word, Any)
+		c.push(top)
+		c.push(top)
+	case SWAP:
+		op1 := c.pop(word, Any)
+		op2 := c.pop(word, Any)
+		c.push(op1)
+		c.push(op2)
+	case ROLL, ROLLD:
+		if c.open || c.inferring {
+			c.unknown()
+		} else if len(c.stack) > 1 {
+			end := len(c.stack) - 1
+			if token.Type == ROLL {
+				c.stack = append(c.stack[end:], c.stack[:end]...)
+			} else {
+				c.stack = append(c.stack[1:], c.stack[0])
+			}
+		}
+	case DEPTH:
+		if c.open || c.inferring {
+			c.push(item{typ: Number})
+		} else {
+			c.push(item{typ: Number, value: float64(len(c.stack)), known: true})
+		}
+	case PICK:
+		n := c.pop(word, Number)
+		if !n.known || c.open || c.inferring {
+			c.unknown()
+			return
+		}
+		index := int(n.value)
+		if index < 0 || len(c.stack) <= index {
+			c.report("Not enough items on the stack to perform this command: %v", word)
+			c.open = true
+			return
+		}
+		c.stack = append(c.stack[:index:index], c.stack[index+1:]...)
+	case DROPN:
+		n := c.pop(word, Number)
+		if !n.known {
+			c.unknown()
+			return
+		}
+		for i := 0; i < int(n.value); i++ {
+			c.pop(word, Any)
+		}
+	case DUPN:
+		n := c.pop(word, Number)
+		if !n.known {
+			c.unknown()
+			return
+		}
+		var temp []item
+		for i := 0; i < int(n.value); i++ {
+			temp = append(temp, c.pop(word, Any))
+		}
+		for i := len(temp) - 1; i >= 0; i-- {
+			c.push(temp[i])
+			c.push(temp[i])
+		}
+	case ASSIGN:
+		variable := c.pop(word, Any)
+		c.registers[token.Argument] = c.typeOf(variable)
+	case EXIT:
+		c.exited = true
+	default:
+		effect, ok := effects[token.Type]
+		if !ok {
+			c.unknown()
+			return
+		}
+		// numeric commands give a vector, matrix or polynomial when they run on one, and a complex number or a
+		// measurement when they run on one of those
+		result := Number
+		vectors, matrices := false, false
+		for i := len(effect.in) - 1; i >= 0; i-- {
+			expected := effect.in[i]
+			if expected == Number && effect.complex != Any && c.peek() == Complex {
+				expected = Complex
+			}
+			typ := c.pop(word, expected).typ
+			if effect.in[i] != Number {
+				continue
+			}
+			vectors, matrices = vectors || typ == Vector, matrices || typ == Matrix
+			switch {
+			case typ == Matrix, typ == Vector && result != Matrix, typ == Polynomial && !isContainer(result),
+				typ == Complex && effect.complex == Complex && !isContainer(result),
+				typ == Measurement && result == Number:
+				result = typ
+			}
+		}
+		if vectors && matrices {
+			// * multiplies a matrix by a vector, and the other commands pair up their elements
+			if token.Type != MULTIPLY {
+				c.report("Expected a matrix or a number on the stack but found a vector: %v", word)
+			}
+			result = Vector
+		}
+		for _, typ := range effect.out {
+			if len(effect.out) == 1 && effect.numeric() && (typ == Number || isContainer(result)) {
+				typ = result
+			}
+			c.push(item{typ: typ})
+		}
+	}
+}
+
+func (c *checker) repeat(n item, word string) {
+	if isSpecialForm(word) {
+		c.unknown()
+		return
+	}
+	if !n.known {
+		c.line([]string{word})
+		c.unknown()
+		return
+	}
+	for i := 0; i < int(n.value) && i < maxRepeat; i++ {
+		c.line([]string{word})
+	}
+	if int(n.value) > maxRepeat {
+		c.unknown()
+	}
+}
+
+// define -> infer the stack effect of a macro from its body
+func (c *checker) define(name string, body []string) {
+	if _, ok := c.calc.keyword(name); ok {
+		c.report("Macro %v can never be called because it is a built-in command", name)
+	} else if _, err := parseValue(name, c.mode, 0); err == nil {
+		c.report("Macro %v can never be called because it is a number", name)
+	}
+
+	// a macro that calls itself has an effect that can't be inferred
+	c.macros[name] = &wordEffect{dynamic: true}
+
+	body = append([]string(nil), body...)
+	inner := &checker{
+		calc:      c.calc,
+		pos:       c.pos,
+		mode:      c.mode,
+		inferring: true,
+		macros:    c.macros,
+		registers: c.registers,
+		problems:  c.problems,
+	}
+	inner.line(body)
+
+	effect := &wordEffect{in: inner.inputs, dynamic: inner.dynamic, exits: inner.exited}
+	if inner.mode != c.mode {
+		effect.mode = inner.mode
+	}
+	for _, out := range inner.stack {
+		out.typ = inner.typeOf(out)
+		effect.out = append(effect.out, out)
+	}
+	c.macros[name] = effect
+}
+
+// apply -> check a call to a macro with an inferred effect
+func (c *checker) apply(name string, effect *wordEffect) {
+	inputs := make([]item, len(effect.in))
+	for i, typ := range effect.in {
+		// what a macro does with numbers isn't inferred finely enough to tell whether it takes complex ones
+		if typ == Number && c.peek() == Complex {
+			typ = Complex
+		}
+		inputs[i] = c.pop(name, typ)
+	}
+	if effect.dynamic {
+		c.unknown()
+	} else {
+		for _, out := range effect.out {
+			if out.input > 0 {
+				out = inputs[out.input-1]
+			}
+			c.push(out)
+		}
+	}
+	if effect.mode != "" {
+		c.mode = effect.mode
+	}
+	if effect.exits {
+		c.exited = true
+	}
+}
+
+func (c *checker) push(element item) {
+	c.stack = append(c.stack, element)
+}
+
+// pop -> take an item of the expected type off the stack, reporting underflows and type mismatches
+func (c *checker) pop(command string, expected Kind) item {
+	length := len(c.stack)
+	if length == 0 {
+		if c.open {
+			return item{typ: expected}
+		}
+		if c.inferring {
+			c.inputs = append(c.inputs, expected)
+			return item{typ: expected, input: len(c.inputs)}
+		}
+		c.report("Not enough items on the stack to perform this command: %v", command)
+		c.open = true
+		return item{typ: expected}
+	}
+
+	var element item
+	c.stack, element = c.stack[:length-1], c.stack[length-1]
+	if expected == Any {
+		return element
+	}
+	actual := c.typeOf(element)
+	if actual == Any {
+		if element.input > 0 {
+			c.inputs[element.input-1] = expected
+		}
+	} else if !compatible(expected, actual) {
+		c.report("Expected %v on the stack but found %v: %v", expected.article(), actual.article(), command)
+	} else if expected == Number {
+		// a vector, matrix or polynomial stays one, for a numeric command to give one back
+		return element
+	}
+	element.typ = expected
+	return element
+}
+
+// compatible -> whether a command that expects one type of item runs on another: numeric commands run on vectors,
+// matrices, polynomials and measurements, and polynomial commands take a number as a constant polynomial
+func compatible(expected, actual Kind) bool {
+	switch {
+	case actual == expected:
+		return true
+	case expected == Number:
+		return isContainer(actual) || actual == Measurement
+	case expected == Polynomial:
+		return actual == Number
+	}
+	return false
+}
+
+// isContainer -> whether a type holds numbers that numeric commands run on one by one
+func isContainer(typ Kind) bool {
+	return typ == Vector || typ == Matrix || typ == Polynomial
+}
+
+// peek -> the type of the item on top of the stack, or Any if it isn't known
+func (c *checker) peek() Kind {
+	if len(c.stack) == 0 {
+		return Any
+	}
+	return c.typeOf(c.stack[len(c.stack)-1])
+}
+
+// typeOf -> the type of an item, as narrowed by how the macro being inferred uses its inputs
+func (c *checker) typeOf(element item) Kind {
+	if element.typ == Any && element.input > 0 {
+		return c.inputs[element.input-1]
+	}
+	return element.typ
+}
+
+// unknown -> forget the stack after a command whose effect can't be known before running
+func (c *checker) unknown() {
+	c.stack = nil
+	c.open = true
+	c.dynamic = true
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCheckScript(t *testing.T) {
+	tests := []struct {
+		script   string
+		problems []string
+	}{
+		{"1 2 +\n[1 2] [3 4] dot 1 +\nmacro sq dup *\n3 sq", nil},
+		{"1 +", []string{"s.rpn:1: Not enough items on the stack to perform this command: +"}},
+		{"1 2\n+ +\n+", []string{"s.rpn:2: Not enough items on the stack to perform this command: +"}},
+		{`"a" 1 +`, []string{"s.rpn:1: Expected a number on the stack but found a string: +"}},
+		{"1 2 < 3 +", []string{"s.rpn:1: Expected a number on the stack but found a boolean: +"}},
+		{"[1 2] det", []string{"s.rpn:1: Expected a matrix on the stack but found a vector: det"}},
+		{"1 exit\n2 3 +\n4", []string{"s.rpn:2: Unreachable code after exit: 2"}},
+		{"1 frobnicate", []string{"s.rpn:1: Unknown command: frobnicate"}},
+		{"disasm sq", []string{"s.rpn:1: Unknown macro: sq"}},
+		{"macro sq dup *\n\"a\" sq", []string{"s.rpn:2: Expected a number on the stack but found a string: sq"}},
+		{"3 x=\nx 1 +\n\"a\" x=\nx 1 +", []string{"s.rpn:4: Expected a number on the stack but found a string: +"}},
+
+		// numeric commands run on vectors, matrices and polynomials, and give them back
+		{"[1 2] 2 * norm\n[[1 2] [3 4]] 2 * det\n[[1 2] [3 4]] [1 2] * norm", nil},
+		{"[1 2] 2 * det", []string{"s.rpn:1: Expected a matrix on the stack but found a vector: det"}},
+		{"[[1 2] [3 4]] [1 2] .*", []string{"s.rpn:1: Expected a matrix or a number on the stack but found a vector: .*"}},
+
+		// complex numbers run through the commands that take them, and no others
+		{"3+4i sqrt 2 * conj abs 1 <\n3 4 r->c c->r +\n[1 2] 3 r->c norm\nmacro mag polar drop\n3+4i mag", nil},
+		{"3 4 r->c 1 <", []string{"s.rpn:1: Expected a number on the stack but found a complex number: <"}},
+		{"3+4i 2 r->c", []string{"s.rpn:1: Expected a number on the stack but found a complex number: r->c"}},
+		{"9.81 0.02 ± 2 * 1 <\n[1 2] 0.1 ± norm", nil},
+
+		// each test starts from the state the lines before the first one leave
+		{"1\ntest: a\n2 +\ntest: b\n+ +", []string{"s.rpn:5: Not enough items on the stack to perform this command: +"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, p := range New().checkScript(parseScript("s.rpn", test.script)) {
+			got = append(got, fmt.Sprintf("%v: %v", p.pos, p.msg))
+		}
+		if strings.Join(got, "\n") != strings.Join(test.problems, "\n") {
+			t.Errorf("%q:\ngot      %q\nexpected %q", test.script, got, test.problems)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package core
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// list -> a list, which holds items of any type. A list of commands is a block that map, filter and
+// the other higher-order commands run.
+type list []Value
+
+// symbol -> a word in a block that isn't a value, kept to be run later
+type symbol string
+
+// parseList -> parse a list literal such as { 1 "two" [3 4] } or a block such as { dup * }, where words that aren't
+// values are kept to be run later
+func parseList(item, mode string, digits int) (Value, bool) {
+	if !strings.HasPrefix(item, "{") || !strings.HasSuffix(item, "}") {
+		return nil, false
+	}
+	elements := make(list, 0)
+	for _, word := range tokenize(item[1 : len(item)-1]) {
+		if element, err := parseValue(word, mode, digits); err == nil {
+			elements = append(elements, element)
+		} else {
+			elements = append(elements, symbol(word))
+		}
+	}
+	return elements, true
+}
+
+func (l list) Kind() Kind {
+	return List
+}
+
+func (l list) show(c *Calculator) string {
+	shown := make([]string, 0, len(l)+2)
+	shown = append(shown, "{")
+	for _, element := range l {
+		shown = append(shown, element.show(c))
+	}
+	return strings.Join(append(shown, "}"), " ")
+}
+
+func (l list) Equal(other Value) bool {
+	m, ok := other.(list)
+	if !ok || len(l) != len(m) {
+		return false
+	}
+	for i, element := range l {
+		if !element.Equal(m[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w symbol) Kind() Kind {
+	return Word
+}
+
+func (w symbol) show(c *Calculator) string {
+	return string(w)
+}
+
+func (w symbol) Equal(other Value) bool {
+	v, ok := other.(symbol)
+	return ok && w == v
+}
+
+// handleList -> run the list commands and the higher-order commands that take blocks
+func (c *Calculator) handleList(token Token) bool {
+	switch token.Type {
+	case TOLIST:
+		n := int(c.popNumber(TOLIST))
+		if n < 0 || len(c.stack) < n {
+			throwNotEnoughElementsError(TOLIST)
+		}
+		elements := append(list(nil), c.stack[len(c.stack)-n:]...)
+		c.stack = c.stack[:len(c.stack)-n]
+		c.pushList(elements)
+	case FROMLIST:
+		elements := c.popList(FROMLIST)
+		for _, element := range elements {
+			c.push(element)
+		}
+		c.pushInteger(big.NewInt(int64(len(elements))))
+	case LEN:
+		if !c.operandsAre(LEN, List) {
+			return false
+		}
+		c.pushInteger(big.NewInt(int64(len(c.popList(LEN)))))
+	case MAP:
+		block := c.popList(MAP)
+		elements := c.popList(MAP)
+		results := make(list, 0, len(elements))
+		for _, element := range elements {
+			results = append(results, c.applyBlock(block, MAP, element)...)
+		}
+		c.pushList(results)
+	case FILTER:
+		block := c.popList(FILTER)
+		elements := c.popList(FILTER)
+		results := make(list, 0, len(elements))
+		for _, element := range elements {
+			result := c.applyBlock(block, FILTER, element)
+			if len(result) != 1 || result[0].Kind() != Boolean {
+				throw("The block must leave a single boolean: %v", FILTER)
+			}
+			if result[0].(boolean) {
+				results = append(results, element)
+			}
+		}
+		c.pushList(results)
+	case REDUCE:
+		block := c.popList(REDUCE)
+		elements := c.popList(REDUCE)
+		if len(elements) == 0 {
+			throw("Cannot reduce an empty list: %v", REDUCE)
+		}
+		c.push(c.accumulate(block, REDUCE, elements[0], elements[1:]))
+	case FOLD:
+		block := c.popList(FOLD)
+		initial, err := c.pop()
+		if err != nil {
+			throwNotEnoughElementsError(FOLD)
+		}
+		elements := c.popList(FOLD)
+		c.push(c.accumulate(block, FOLD, initial, elements))
+	case EACH:
+		block := c.popList(EACH)
+		for _, element := range c.popList(EACH) {
+			c.push(element)
+			c.runBlock(block)
+		}
+	case ZIP:
+		op1 := c.popList(ZIP)
+		op2 := c.popList(ZIP)
+		if len(op1) != len(op2) {
+			throw("Lists of different lengths %v and %v: %v", len(op2), len(op1), ZIP)
+		}
+		pairs := make(list, len(op1))
+		for i := range op1 {
+			pairs[i] = list{op2[i], op1[i]}
+		}
+		c.pushList(pairs)
+	case SORT:
+		elements := append(list(nil), c.popList(SORT)...)
+		sort.SliceStable(elements, func(i, j int) bool {
+			return c.less(elements[i], elements[j])
+		})
+		c.pushList(elements)
+	case REVERSE:
+		elements := c.popList(REVERSE)
+		reversed := make(list, len(elements))
+		for i, element := range elements {
+			reversed[len(elements)-1-i] = element
+		}
+		c.pushList(reversed)
+	case RANGE:
+		end := c.popExactInteger(RANGE)
+		start := c.popExactInteger(RANGE)
+		c.checkSize(integerToFloat(new(big.Int).Sub(end, start)))
+		elements := make(list, 0)
+		for i := start; i.Cmp(end) < 0; i = new(big.Int).Add(i, big.NewInt(1)) {
+			elements = append(elements, integer{i})
+		}
+		c.pushList(elements)
+	case EQ, NOTEQ:
+		if !c.anyOperand(token.Type, List) {
+			return false
+		}
+		op1, _ := c.pop()
+		op2, _ := c.pop()
+		c.push(boolean(op2.Equal(op1) == (token.Type == EQ)))
+	default:
+		return false
+	}
+	return true
+}
+
+// runBlock -> run a block, pushing its values and running its words together so that repeat and macro work in blocks
+func (c *Calculator) runBlock(block list) {
+	var words []string
+	for _, element := range block {
+		if word, ok := element.(symbol); ok {
+			words = append(words, string(word))
+			continue
+		}
+		if len(words) > 0 {
+			c.eval(words)
+			words = nil
+		}
+		c.push(element)
+	}
+	if len(words) > 0 {
+		c.eval(words)
+	}
+}
+
+// applyBlock -> run a block on some arguments, returning what it leaves on the stack
+func (c *Calculator) applyBlock(block list, command string, args ...Value) []Value {
+	depth := len(c.stack)
+	c.stack = append(c.stack, args...)
+	c.runBlock(block)
+	if len(c.stack) < depth {
+		throw("The block took more items than it was given: %v", command)
+	}
+	results := append([]Value(nil), c.stack[depth:]...)
+	c.stack = c.stack[:depth]
+	return results
+}
+
+// accumulate -> combine the elements of a list one at a time with a block, starting from an initial value
+func (c *Calculator) accumulate(block list, command string, initial Value, elements list) Value {
+	for _, element := range elements {
+		result := c.applyBlock(block, command, initial, element)
+		if len(result) != 1 {
+			throw("The block must leave a single item: %v", command)
+		}
+		initial = result[0]
+	}
+	return initial
+}
+
+// less -> the order of items in a sorted list, with numbers in order before strings in order
+func (c *Calculator) less(a, b Value) bool {
+	x, ok1 := a.(text)
+	y, ok2 := b.(text)
+	if ok1 && ok2 {
+		return x < y
+	}
+	if ok1 || ok2 {
+		return ok2
+	}
+	result, ok := c.compute(LT, a, b).(boolean)
+	if !ok {
+		throw("Cannot sort %v and %v: %v", a.Kind().article(), b.Kind().article(), SORT)
+	}
+	return bool(result)
+}
+
+// popExactInteger -> pop a number that must be a whole number
+func (c *Calculator) popExactInteger(command string) *big.Int {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	x, ok := ratValue(item)
+	if !ok || !x.IsInt() {
+		throwWrongElementType(Integer, item.Kind())
+	}
+	return new(big.Int).Set(x.Num())
+}
+
+func (c *Calculator) pushList(elements list) {
+	c.push(elements)
+}
+
+func (c *Calculator) popList(command string) list {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	elements, ok := item.(list)
+	if !ok {
+		throwWrongElementType(List, item.Kind())
+	}
+	return elements
+}
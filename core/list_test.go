@@ -0,0 +1,23 @@
+package core
+
+import "testing"
+
+func TestList(t *testing.T) {
+	testResults(t, []resultTest{
+		{"{ 1 2 3 } { 2 * } map", "{ 2 4 6 }"},
+		{"{ 1 2 3 4 } { 2 % 0 == } filter", "{ 2 4 }"},
+		{"{ 1 2 3 4 } { + } reduce", "10"},
+		{"{ 1 2 3 } 10 { + } fold", "16"},
+		{"0 { 1 2 3 } { + } each", "6"},
+		{"{ 1 2 3 } { 1 3 / * } map { + } reduce", "2"},
+		{"{ 1 2 } { 3 4 } zip", "{ { 1 3 } { 2 4 } }"},
+		{"{ 3 1 2 } sort", "{ 1 2 3 }"},
+		{"{ 1 2 3 } reverse", "{ 3 2 1 }"},
+		{"1 5 range", "{ 1 2 3 4 }"},
+		{"1 2 3 3 ->list", "{ 1 2 3 }"},
+		{"{ 1 2 3 } list->", "1, 2, 3, 3"},
+		{`{ 1 "a" [1 2] { 2 3 } } len`, "4"},
+		{"{ } { + } reduce", "error: Cannot reduce an empty list: reduce"},
+		{"{ 1 2 3 } 1 map", "error: Expected a list on the stack but found an integer"},
+	})
+}
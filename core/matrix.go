@@ -0,0 +1,435 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+// epsilon -> floats smaller than this are taken to be zero when reducing a matrix
+const epsilon = 1e-12
+
+// matrix -> the rows of a matrix, which all have the same number of elements
+type matrix [][]Value
+
+// toMatrix -> a vector of vectors of the same length is a matrix
+func toMatrix(elements []Value) (matrix, bool) {
+	if len(elements) == 0 {
+		return nil, false
+	}
+	rows := make(matrix, len(elements))
+	for i, element := range elements {
+		row, ok := element.(vector)
+		if !ok {
+			return nil, false
+		}
+		rows[i] = row
+		if len(rows[i]) == 0 || len(rows[i]) != len(rows[0]) {
+			return nil, false
+		}
+	}
+	return rows, true
+}
+
+func (m matrix) Kind() Kind {
+	return Matrix
+}
+
+func (m matrix) show(c *Calculator) string {
+	shown := make([]string, len(m))
+	for i, row := range m {
+		shown[i] = vector(row).show(c)
+	}
+	return "[" + strings.Join(shown, " ") + "]"
+}
+
+func (m matrix) Equal(other Value) bool {
+	n, ok := other.(matrix)
+	if !ok || len(m) != len(n) {
+		return false
+	}
+	for i := range m {
+		if !vector(m[i]).Equal(vector(n[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatMatrixLines -> show a matrix one row per line with its columns lined up, for the vertical stack display
+func (c *Calculator) formatMatrixLines(rows [][]Value) string {
+	cells := make([][]string, len(rows))
+	widths := make([]int, len(rows[0]))
+	for i, row := range rows {
+		cells[i] = make([]string, len(row))
+		for j, element := range row {
+			cells[i][j] = element.show(c)
+			if len(cells[i][j]) > widths[j] {
+				widths[j] = len(cells[i][j])
+			}
+		}
+	}
+
+	lines := make([]string, len(rows))
+	for i, row := range cells {
+		for j, cell := range row {
+			row[j] = strings.Repeat(" ", widths[j]-len(cell)) + cell
+		}
+		lines[i] = " [" + strings.Join(row, " ") + "]"
+	}
+	lines[0] = "[" + lines[0][1:]
+	return strings.Join(lines, "\n") + "]"
+}
+
+// handleMatrix -> run the matrix commands, multiply matrices and apply numeric commands to them element by element
+func (c *Calculator) handleMatrix(token Token) bool {
+	switch token.Type {
+	case IDENTITY:
+		n := int(c.popNumber(IDENTITY))
+		if n < 1 {
+			throw("An identity matrix needs at least 1 row: %v", IDENTITY)
+		}
+		c.checkSize(float64(n) * float64(n))
+		c.pushMatrix(identity(n))
+	case TRANSPOSE:
+		c.pushMatrix(transpose(c.popMatrix(TRANSPOSE)))
+	case TRACE:
+		op1 := square(c.popMatrix(TRACE), TRACE)
+		diagonal := make([]Value, len(op1))
+		for i := range op1 {
+			diagonal[i] = op1[i][i]
+		}
+		c.push(c.sum(diagonal))
+	case DET:
+		_, _, det := c.reduce(square(c.popMatrix(DET), DET), 0)
+		c.push(det)
+	case RANK:
+		_, rank, _ := c.reduce(c.popMatrix(RANK), 0)
+		c.pushInteger(big.NewInt(int64(rank)))
+	case INV:
+		op1 := square(c.popMatrix(INV), INV)
+		reduced, rank, _ := c.reduce(augment(op1, identity(len(op1))), len(op1))
+		if rank < len(op1) {
+			throw("The matrix is singular: %v", INV)
+		}
+		c.pushMatrix(columns(reduced, len(op1), 2*len(op1)))
+	case SOLVE:
+		item, err := c.pop()
+		if err != nil {
+			throwNotEnoughElementsError(SOLVE)
+		}
+		op1 := square(c.popMatrix(SOLVE), SOLVE)
+		b, isVector := matrixValue(item)
+		if isVector {
+			b = transpose(b)
+		}
+		if len(b) != len(op1) {
+			throw("The right hand side has %v rows but the matrix has %v: %v", len(b), len(op1), SOLVE)
+		}
+		reduced, rank, _ := c.reduce(augment(op1, b), len(op1))
+		if rank < len(op1) {
+			throw("The matrix is singular: %v", SOLVE)
+		}
+		x := columns(reduced, len(op1), len(op1)+len(b[0]))
+		if isVector {
+			c.pushVector(transpose(x)[0])
+		} else {
+			c.pushMatrix(x)
+		}
+	case LU:
+		l, u, p := c.decompose(square(c.popMatrix(LU), LU))
+		c.pushMatrix(l)
+		c.pushMatrix(u)
+		c.pushMatrix(p)
+	case ELEMMUL:
+		if !c.anyOperand(ELEMMUL, Matrix) && !c.anyOperand(ELEMMUL, Vector) {
+			token.Type = MULTIPLY
+			c.handleCommand(token)
+			return true
+		}
+		op1, _ := c.pop()
+		op2, _ := c.pop()
+		c.push(c.elementwiseValue(MULTIPLY, op2, op1))
+	case MULTIPLY:
+		if !c.anyOperand(MULTIPLY, Matrix) {
+			return false
+		}
+		op1, _ := c.pop()
+		op2, _ := c.pop()
+		c.push(c.product(op2, op1))
+	case EQ, NOTEQ:
+		if !c.anyOperand(token.Type, Matrix) {
+			return false
+		}
+		op1, _ := c.pop()
+		op2, _ := c.pop()
+		c.push(boolean(op2.Equal(op1) == (token.Type == EQ)))
+	default:
+		effect := effects[token.Type]
+		if !c.anyOperand(token.Type, Matrix) || !effect.numeric() {
+			return false
+		}
+		switch len(effect.in) {
+		case 1:
+			op1, _ := c.pop()
+			c.push(c.elementwiseValue(token.Type, op1))
+		case 2:
+			op1, _ := c.pop()
+			op2, _ := c.pop()
+			c.push(c.elementwiseValue(token.Type, op2, op1))
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// elementwiseValue -> run a command on the matching elements of matrices, vectors and scalars
+func (c *Calculator) elementwiseValue(command string, operands ...Value) Value {
+	var rows matrix
+	for _, operand := range operands {
+		if m, ok := operand.(matrix); ok {
+			rows = m
+		}
+	}
+	if rows == nil {
+		elements := make([][]Value, len(operands))
+		for i, operand := range operands {
+			elements[i] = []Value{operand}
+			if v, ok := operand.(vector); ok {
+				elements[i] = v
+			}
+		}
+		return vector(c.elementwise(command, elements...))
+	}
+
+	result := make(matrix, len(rows))
+	for i := range rows {
+		row := make([][]Value, len(operands))
+		for j, operand := range operands {
+			switch other := operand.(type) {
+			case matrix:
+				if len(other) != len(rows) || len(other[0]) != len(rows[0]) {
+					throw("Matrices have different sizes: %v", command)
+				}
+				row[j] = other[i]
+			case vector:
+				throw("Expected a matrix or a number on the stack but found a vector: %v", command)
+			default:
+				row[j] = []Value{operand}
+			}
+		}
+		result[i] = c.elementwise(command, row...)
+	}
+	return result
+}
+
+// product -> multiply matrices, a matrix by a vector as a column or a vector as a row by a matrix, or a matrix by a scalar
+func (c *Calculator) product(a, b Value) Value {
+	if a.Kind() != Matrix && a.Kind() != Vector || b.Kind() != Matrix && b.Kind() != Vector {
+		return c.elementwiseValue(MULTIPLY, a, b)
+	}
+	x, rowVector := matrixValue(a)
+	y, columnVector := matrixValue(b)
+	if columnVector {
+		y = transpose(y)
+	}
+	if len(x[0]) != len(y) {
+		throw("Cannot multiply a %vx%v matrix by a %vx%v matrix", len(x), len(x[0]), len(y), len(y[0]))
+	}
+
+	c.checkSize(float64(len(x)) * float64(len(y[0])))
+	result := make(matrix, len(x))
+	for i := range x {
+		c.checkDone()
+		result[i] = make([]Value, len(y[0]))
+		for j := range y[0] {
+			column := make([]Value, len(y))
+			for k := range y {
+				column[k] = y[k][j]
+			}
+			result[i][j] = c.dot(x[i], column)
+		}
+	}
+	switch {
+	case columnVector:
+		return vector(transpose(result)[0])
+	case rowVector:
+		return vector(result[0])
+	}
+	return result
+}
+
+// reduce -> reduce the first n columns of a matrix to reduced row echelon form using partial pivoting, or all of them
+// if n is 0, returning the result, its rank and the determinant of those columns
+func (c *Calculator) reduce(m [][]Value, n int) ([][]Value, int, Value) {
+	if n == 0 {
+		n = len(m[0])
+	}
+	rows := make([][]Value, len(m))
+	for i := range m {
+		rows[i] = append([]Value(nil), m[i]...)
+	}
+
+	var det Value = integer{big.NewInt(1)}
+	rank := 0
+	for column := 0; column < n && rank < len(rows); column++ {
+		c.checkDone()
+		pivot, largest := -1, 0.0
+		for i := rank; i < len(rows); i++ {
+			if size := magnitudeOf(rows[i][column]); !isZero(rows[i][column]) && size > largest {
+				pivot, largest = i, size
+			}
+		}
+		if pivot < 0 {
+			det = integer{new(big.Int)}
+			continue
+		}
+		if pivot != rank {
+			rows[pivot], rows[rank] = rows[rank], rows[pivot]
+			det = c.compute(MINUS, integer{new(big.Int)}, det)
+		}
+
+		value := rows[rank][column]
+		det = c.compute(MULTIPLY, det, value)
+		rows[rank] = c.elementwise(DIVIDE, rows[rank], []Value{value})
+		for i := range rows {
+			if i != rank && !isZero(rows[i][column]) {
+				scaled := c.elementwise(MULTIPLY, rows[rank], []Value{rows[i][column]})
+				rows[i] = c.elementwise(MINUS, rows[i], scaled)
+			}
+		}
+		rank++
+	}
+	if rank < n {
+		det = integer{new(big.Int)}
+	}
+	return rows, rank, det
+}
+
+// decompose -> the LU decomposition of a square matrix with partial pivoting, where P A = L U
+func (c *Calculator) decompose(m [][]Value) ([][]Value, [][]Value, [][]Value) {
+	n := len(m)
+	u := make([][]Value, n)
+	for i := range m {
+		u[i] = append([]Value(nil), m[i]...)
+	}
+	l, p := identity(n), identity(n)
+	for column := 0; column < n; column++ {
+		c.checkDone()
+		pivot, largest := column, magnitudeOf(u[column][column])
+		for i := column + 1; i < n; i++ {
+			if size := magnitudeOf(u[i][column]); size > largest {
+				pivot, largest = i, size
+			}
+		}
+		if pivot != column {
+			u[pivot], u[column] = u[column], u[pivot]
+			p[pivot], p[column] = p[column], p[pivot]
+			for k := 0; k < column; k++ {
+				l[pivot][k], l[column][k] = l[column][k], l[pivot][k]
+			}
+		}
+		if isZero(u[column][column]) {
+			continue
+		}
+		for i := column + 1; i < n; i++ {
+			factor := c.compute(DIVIDE, u[i][column], u[column][column])
+			l[i][column] = factor
+			u[i] = c.elementwise(MINUS, u[i], c.elementwise(MULTIPLY, u[column], []Value{factor}))
+		}
+	}
+	return l, u, p
+}
+
+func identity(n int) [][]Value {
+	rows := make([][]Value, n)
+	for i := range rows {
+		rows[i] = make([]Value, n)
+		for j := range rows[i] {
+			rows[i][j] = integer{new(big.Int)}
+		}
+		rows[i][i] = integer{big.NewInt(1)}
+	}
+	return rows
+}
+
+func transpose(m [][]Value) [][]Value {
+	result := make([][]Value, len(m[0]))
+	for j := range result {
+		result[j] = make([]Value, len(m))
+		for i := range m {
+			result[j][i] = m[i][j]
+		}
+	}
+	return result
+}
+
+// augment -> put the columns of b to the right of a
+func augment(a, b [][]Value) [][]Value {
+	result := make([][]Value, len(a))
+	for i := range a {
+		result[i] = append(append([]Value(nil), a[i]...), b[i]...)
+	}
+	return result
+}
+
+// columns -> the columns of a matrix from start up to end
+func columns(m [][]Value, start, end int) [][]Value {
+	result := make([][]Value, len(m))
+	for i := range m {
+		result[i] = m[i][start:end]
+	}
+	return result
+}
+
+func square(m [][]Value, command string) [][]Value {
+	if len(m) != len(m[0]) {
+		throw("Expected a square matrix but found a %vx%v matrix: %v", len(m), len(m[0]), command)
+	}
+	return m
+}
+
+func magnitudeOf(item Value) float64 {
+	number, _ := numberValue(item)
+	return math.Abs(number)
+}
+
+// isZero -> whether an element is zero, within epsilon for floats
+func isZero(item Value) bool {
+	if x, ok := exactValue(item); ok {
+		return x.Sign() == 0
+	}
+	return magnitudeOf(item) < epsilon
+}
+
+func (c *Calculator) pushMatrix(rows matrix) {
+	c.push(rows)
+}
+
+func (c *Calculator) popMatrix(command string) matrix {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	rows, ok := item.(matrix)
+	if !ok {
+		throwWrongElementType(Matrix, item.Kind())
+	}
+	return rows
+}
+
+// matrixValue -> the rows of a matrix, or a vector as a single row
+func matrixValue(item Value) (matrix, bool) {
+	switch x := item.(type) {
+	case matrix:
+		return x, false
+	case vector:
+		if len(x) == 0 {
+			throw("Expected a matrix or vector with elements")
+		}
+		return matrix{x}, true
+	}
+	throwWrongElementType(Matrix, item.Kind())
+	return nil, false
+}
@@ -0,0 +1,33 @@
+package core
+
+import "testing"
+
+func TestMatrix(t *testing.T) {
+	testResults(t, []resultTest{
+		{"[[1 2] [3 4]] [[5 6] [7 8]] *", "[[19 22] [43 50]]"},
+		{"[[1 2] [3 4]] [[5 6] [7 8]] .*", "[[5 12] [21 32]]"},
+		{"[[1 2] [3 4]] [1 2] *", "[5 11]"},
+		{"[[1 2] [3 4]] 2 *", "[[2 4] [6 8]]"},
+		{"[[1 2] [3 4]] transpose", "[[1 3] [2 4]]"},
+		{"[[1 2] [3 4]] trace", "5"},
+		{"2 identity", "[[1 0] [0 1]]"},
+		{"[[1 2] [2 4]] rank", "1"},
+		{"[[1 2 3] [4 5 6]] rank", "2"},
+
+		// integer matrices are reduced exactly
+		{"[[1 2] [3 4]] det", "-2"},
+		{"[[2 0 1] [1 3 2] [1 1 2]] det", "6"},
+		{"[[1 2] [3 4]] inv", "[[-2 1] [3/2 -1/2]]"},
+		{"[[2 0 1] [1 3 2] [1 1 2]] inv", "[[2/3 1/6 -1/2] [0 1/2 -1/2] [-1/3 -1/3 1]]"},
+		{"[[2 0 1] [1 3 2] [1 1 2]] dup inv *", "[[1 0 0] [0 1 0] [0 0 1]]"},
+		{"[[2 1] [1 3]] [3 5] solve", "[4/5 7/5]"},
+		{"[[2 0 1] [1 3 2] [1 1 2]] [1 2 3] solve", "[-1/2 -1/2 2]"},
+		{"[[2.0 1] [1 3]] [3 5] solve", "[0.8 1.4]"},
+		{"[[4 3] [6 3]] lu", "[[1 0] [2/3 1]], [[6 3] [0 1]], [[0 1] [1 0]]"},
+
+		{"[[1 2] [2 4]] inv", "error: The matrix is singular: inv"},
+		{"[[1 2] [2 4]] [1 2] solve", "error: The matrix is singular: solve"},
+		{"[[1 2 3] [4 5 6]] det", "error: Expected a square matrix but found a 2x3 matrix: det"},
+		{"[[1 2] [3 4]] [[1 2 3]] *", "error: Cannot multiply a 2x2 matrix by a 1x3 matrix"},
+	})
+}
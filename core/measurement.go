@@ -0,0 +1,181 @@
+package core
+
+import (
+	"math"
+	"strconv"
+)
+
+// measurement -> a value with an uncertainty, kept as how much each independent source of error contributes to it
+// so that reusing the same measurement twice is correlated
+type measurement struct {
+	value float64
+	terms map[int]float64
+}
+
+func (m *measurement) uncertainty() float64 {
+	sum := 0.0
+	for _, term := range m.terms {
+		sum += term * term
+	}
+	return math.Sqrt(sum)
+}
+
+func (m *measurement) Kind() Kind {
+	return Measurement
+}
+
+// String -> show the uncertainty to 2 significant figures and the value to the same decimal place
+func (m *measurement) show(c *Calculator) string {
+	sigma := m.uncertainty()
+	if sigma == 0 || math.IsNaN(sigma) || math.IsInf(sigma, 0) {
+		return strconv.FormatFloat(m.value, 'f', -1, 64) + " ± " + strconv.FormatFloat(sigma, 'f', -1, 64)
+	}
+	places := 1 - int(math.Floor(math.Log10(sigma)))
+	return roundPlaces(m.value, places) + " ± " + roundPlaces(sigma, places)
+}
+
+// Equal -> whether a measurement has the same value and uncertainty as another, or a number as one without any
+func (m *measurement) Equal(other Value) bool {
+	if _, ok := other.(significant); ok {
+		return other.Equal(m)
+	}
+	n, ok := measurementValue(other)
+	return ok && m.value == n.value && m.uncertainty() == n.uncertainty()
+}
+
+func roundPlaces(x float64, places int) string {
+	if places >= 0 {
+		unit := math.Pow(10, float64(places))
+		return strconv.FormatFloat(math.Round(x*unit)/unit, 'f', -1, 64)
+	}
+	unit := math.Pow(10, float64(-places))
+	return strconv.FormatFloat(math.Round(x/unit)*unit, 'f', -1, 64)
+}
+
+// handlePlusMinus -> give a number or measurement another independent uncertainty
+func (c *Calculator) handlePlusMinus(token Token) bool {
+	if token.Type != PLUSMINUS {
+		return false
+	}
+	op1 := c.popNumber(PLUSMINUS)
+	op2 := c.popMeasurement(PLUSMINUS)
+	c.sources++
+	c.pushMeasurement(op2.value, part{op2, 1}, part{&measurement{terms: map[int]float64{c.sources: math.Abs(op1)}}, 1})
+	return true
+}
+
+// handleMeasurement -> run a command on measurements when one of its operands is a measurement,
+// propagating the uncertainties to first order
+func (c *Calculator) handleMeasurement(token Token) bool {
+	switch token.Type {
+	case PLUS:
+		op1 := c.popMeasurement(PLUS)
+		op2 := c.popMeasurement(PLUS)
+		c.pushMeasurement(op2.value+op1.value, part{op2, 1}, part{op1, 1})
+	case MINUS:
+		op1 := c.popMeasurement(MINUS)
+		op2 := c.popMeasurement(MINUS)
+		c.pushMeasurement(op2.value-op1.value, part{op2, 1}, part{op1, -1})
+	case MULTIPLY:
+		op1 := c.popMeasurement(MULTIPLY)
+		op2 := c.popMeasurement(MULTIPLY)
+		c.pushMeasurement(op2.value*op1.value, part{op2, op1.value}, part{op1, op2.value})
+	case DIVIDE:
+		op1 := c.popMeasurement(DIVIDE)
+		op2 := c.popMeasurement(DIVIDE)
+		c.pushMeasurement(op2.value/op1.value, part{op2, 1 / op1.value}, part{op1, -op2.value / (op1.value * op1.value)})
+	case POW:
+		op1 := c.popMeasurement(POW)
+		op2 := c.popMeasurement(POW)
+		value := math.Pow(op2.value, op1.value)
+		// the exponent only contributes if it is uncertain, which needs a positive base
+		exponent := 0.0
+		if len(op1.terms) > 0 {
+			exponent = value * math.Log(op2.value)
+		}
+		c.pushMeasurement(value, part{op2, op1.value * math.Pow(op2.value, op1.value-1)}, part{op1, exponent})
+	case DECR, INCR:
+		op1 := c.popMeasurement(token.Type)
+		step := 1.0
+		if token.Type == DECR {
+			step = -1
+		}
+		c.pushMeasurement(op1.value+step, part{op1, 1})
+	case ABS:
+		op1 := c.popMeasurement(ABS)
+		sign := 1.0
+		if op1.value < 0 {
+			sign = -1
+		}
+		c.pushMeasurement(math.Abs(op1.value), part{op1, sign})
+
+	case SQRT:
+		c.apply(SQRT, math.Sqrt, func(x float64) float64 { return 1 / (2 * math.Sqrt(x)) })
+	case EXP:
+		c.apply(EXP, math.Exp, math.Exp)
+	case LN:
+		c.apply(LN, math.Log, func(x float64) float64 { return 1 / x })
+	case LOG:
+		c.apply(LOG, math.Log10, func(x float64) float64 { return 1 / (x * math.Ln10) })
+	case SIN:
+		c.apply(SIN, math.Sin, math.Cos)
+	case COS:
+		c.apply(COS, math.Cos, func(x float64) float64 { return -math.Sin(x) })
+
+	case LT, LTOREQ, NOTEQ, EQ, GT, GTOREQ:
+		// measurements compare by their values
+		op1 := c.popMeasurement(token.Type)
+		op2 := c.popMeasurement(token.Type)
+		c.push(float(op2.value))
+		c.push(float(op1.value))
+		c.handleCommand(token)
+	default:
+		return false
+	}
+	return true
+}
+
+// apply -> apply a function with the given derivative to the measurement on top of the stack
+func (c *Calculator) apply(command string, f, derivative func(float64) float64) {
+	op1 := c.popMeasurement(command)
+	c.pushMeasurement(f(op1.value), part{op1, derivative(op1.value)})
+}
+
+// part -> an operand of a calculation on measurements and the partial derivative of the result with respect to it
+type part struct {
+	m          *measurement
+	derivative float64
+}
+
+// pushMeasurement -> push a value whose error is the sum of the errors of its operands times their partial derivatives
+func (c *Calculator) pushMeasurement(value float64, parts ...part) {
+	terms := make(map[int]float64)
+	for _, p := range parts {
+		for source, term := range p.m.terms {
+			terms[source] += p.derivative * term
+		}
+	}
+	c.push(&measurement{value, terms})
+}
+
+// popMeasurement -> pop a measurement, treating a number as one without any uncertainty
+func (c *Calculator) popMeasurement(command string) *measurement {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	m, ok := measurementValue(item)
+	if !ok {
+		throwWrongElementType(Number, item.Kind())
+	}
+	return m
+}
+
+// measurementValue -> a measurement, or a number as one without any uncertainty
+func measurementValue(item Value) (*measurement, bool) {
+	if m, ok := item.(*measurement); ok {
+		return m, true
+	}
+	number, ok := numberValue(item)
+	return &measurement{value: number}, ok
+}
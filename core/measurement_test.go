@@ -0,0 +1,24 @@
+package core
+
+import "testing"
+
+func TestMeasurement(t *testing.T) {
+	testResults(t, []resultTest{
+		{"9.81 0.02 ±", "9.81 ± 0.02"},
+		{"9.81 0.02 +/-", "9.81 ± 0.02"},
+		{"9.81 0.02 ± 2 *", "19.62 ± 0.04"},
+		{"1 0.1 ± 2 0.1 ± +", "3 ± 0.14"},
+		{"1 0.1 ± 2 0.1 ± /", "0.5 ± 0.056"},
+		{"2 0.1 ± 3 pow", "8 ± 1.2"},
+		{"4 0.2 ± sqrt", "2 ± 0.05"},
+		{"0 0.1 ± sin", "0 ± 0.1"},
+		{"1 0.1 ± ln", "0 ± 0.1"},
+
+		// a value used twice is correlated with itself, unlike two measurements of the same size
+		{"9.81 0.02 ± x= x x -", "0 ± 0"},
+		{"9.81 0.02 ± x= x x /", "1 ± 0"},
+		{"9.81 0.02 ± x= x x +", "19.62 ± 0.04"},
+		{"9.81 0.02 ± x= x x *", "96.24 ± 0.39"},
+		{"9.81 0.02 ± 9.81 0.02 ± -", "0 ± 0.028"},
+	})
+}
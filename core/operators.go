@@ -0,0 +1,621 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// operator -> a built-in command: how it is spelled, what it does to the stack, how it is described and how it runs
+type operator struct {
+	// name -> the canonical spelling, which is also the type of its tokens
+	name string
+	// aliases -> other spellings, rewritten to the name by rpn fmt
+	aliases []string
+	// argument -> passed to the command in its token, for commands that share an implementation
+	argument string
+	// effect -> the types the command pops and pushes, or nil if they depend on the stack
+	effect *effect
+	// impure -> the command depends on or changes more than its operands, so it can't be run while compiling
+	impure bool
+	help   string
+	// examples -> calculations using the command, whose results are worked out by running them
+	examples []string
+	// run -> run the command on float64s, once the handlers for other types have passed it up. Commands with no
+	// implementation of their own, such as repeat and macro, are nil.
+	run func(*Calculator, Token)
+	// custom -> the command was registered by a program embedding the calculator, and runs on its operands as they
+	// are instead of going through the handlers
+	custom bool
+	// args -> the types a registered command pops, bottom first. What it pushes isn't known until it runs.
+	args []Kind
+}
+
+// group -> commands that are listed together in the help
+type group struct {
+	title     string
+	operators []operator
+}
+
+// builtins -> every built-in command, in the order they are listed in the help
+var builtins []group
+
+// operators -> the built-in commands by name
+var operators = make(map[string]*operator)
+
+// aliases -> alternative spellings of commands, rewritten to the canonical spelling by rpn fmt
+var aliases = make(map[string]string)
+
+// effects -> the stack effects of the commands that always pop and push the same types.
+// Commands that shuffle the stack or depend on its contents are handled by the checker.
+var effects = make(map[string]effect)
+
+func init() {
+	builtins = []group{
+		{"Arithmetic", []operator{
+			{name: PLUS, help: "add two numbers", effect: binaryComplex, examples: []string{"1 2 +"},
+				run: binary(func(x, y float64) float64 { return x + y })},
+			{name: MINUS, help: "subtract the top number from the one below it", effect: binaryComplex,
+				examples: []string{"5 3 -"}, run: binary(func(x, y float64) float64 { return x - y })},
+			{name: MULTIPLY, help: "multiply two numbers", effect: binaryComplex, examples: []string{"6 7 *"},
+				run: binary(func(x, y float64) float64 { return x * y })},
+			{name: DIVIDE, help: "divide the number below the top by the top one", effect: binaryComplex,
+				examples: []string{"7 2 /", "7.5 2 /"}, run: binary(func(x, y float64) float64 { return x / y })},
+			{name: DIVINT, help: "integer division, truncating towards zero", effect: binaryNumber,
+				examples: []string{"7 2 div"}, run: binary(func(x, y float64) float64 { return math.Trunc(x / y) })},
+			{name: MOD, aliases: []string{"mod"}, help: "modulus", effect: binaryNumber, examples: []string{"7 3 %"},
+				run: binary(math.Mod)},
+			{name: DECR, help: "decrement", effect: unaryComplex, examples: []string{"5 --"},
+				run: unary(func(x float64) float64 { return x - 1 })},
+			{name: INCR, help: "increment", effect: unaryComplex, examples: []string{"5 ++"},
+				run: unary(func(x float64) float64 { return x + 1 })},
+			{name: POW, help: "raise a number to a power", effect: binaryComplex, examples: []string{"2 10 pow"},
+				run: binary(math.Pow)},
+			{name: SQRT, help: "square root", effect: unaryComplex, examples: []string{"2 sqrt"}, run: unary(math.Sqrt)},
+			{name: EXP, help: "exponential", effect: unaryComplex, examples: []string{"1 exp"}, run: unary(math.Exp)},
+			{name: LN, help: "natural log", effect: unaryComplex, examples: []string{"e ln"}, run: unary(math.Log)},
+			{name: LOG, help: "base 10 logarithm", effect: unaryComplex, examples: []string{"1000 log"},
+				run: unary(math.Log10)},
+			{name: FACT, help: "factorial", effect: unaryNumber, examples: []string{"5 fact"}, run: unary(factorial)},
+			{name: ABS, help: "absolute value", effect: complexPart, examples: []string{"-3 abs"}, run: unary(math.Abs)},
+			{name: SIGN, help: "-1, 0 or 1 as a number is negative, zero or positive",
+				effect: unaryNumber, examples: []string{"-2.5 sign", "0 sign", "7/2 sign"}, run: unary(sign)},
+			{name: CEIL, aliases: []string{"ceiling"}, help: "round up to a whole number", effect: unaryNumber,
+				examples: []string{"1.2 ceil"}, run: unary(math.Ceil)},
+			{name: FLOOR, help: "round down to a whole number", effect: unaryNumber, examples: []string{"1.8 floor"},
+				run: unary(math.Floor)},
+			{name: ROUND, help: "round to the nearest whole number, halves away from zero", effect: unaryNumber,
+				examples: []string{"2.5 round"}, run: unary(math.Round)},
+			{name: IP, help: "integer part", effect: unaryNumber, examples: []string{"3.75 ip"},
+				run: unary(func(x float64) float64 { return float64(int(x)) })},
+			{name: FP, help: "fractional part", effect: unaryNumber, examples: []string{"3.75 fp"},
+				run: unary(func(x float64) float64 { return x - float64(int(x)) })},
+			{name: MAX, help: "larger of two numbers", effect: binaryNumber, examples: []string{"2 3 max"},
+				run: binary(math.Max)},
+			{name: MIN, help: "smaller of two numbers", effect: binaryNumber, examples: []string{"2 3 min"},
+				run: binary(math.Min)},
+		}},
+		{"Fractions", []operator{
+			{name: NUM, help: "numerator of a fraction", effect: unaryNumber, examples: []string{"3/4 num"}},
+			{name: DEN, help: "denominator of a fraction", effect: unaryNumber, examples: []string{"3/4 den"}},
+			{name: TOFRAC, help: "approximate a number by a fraction whose denominator is at most n",
+				effect: binaryNumber, examples: []string{"pi 1000 ->q"}, run: (*Calculator).toFrac},
+			{name: MIXED, help: "toggle fraction display between 3/2 and 1 1/2", effect: noEffect, impure: true,
+				examples: []string{"mixed 7/4"}, run: (*Calculator).toggleMixed},
+		}},
+		{"Constants and precision", []operator{
+			{name: PI, help: "pi", effect: &effect{out: []Kind{Number}}, examples: []string{"pi"},
+				run: constant(math.Pi)},
+			{name: E, help: "e", effect: &effect{out: []Kind{Number}}, examples: []string{"e"}, run: constant(math.E)},
+			{name: RAND, help: "random number from 0 up to 1", effect: &effect{out: []Kind{Number}}, impure: true,
+				run: (*Calculator).random},
+			{name: SEED, help: "seed the random numbers, so that rand gives the same ones every time",
+				effect: &effect{in: []Kind{Number}}, impure: true, run: (*Calculator).seed},
+			{name: PREC, help: "set the number of significant digits, or 0 for float64", effect: &effect{in: []Kind{Number}},
+				impure: true, examples: []string{"30 prec 2 sqrt"}, run: (*Calculator).setPrecision},
+		}},
+		{"Comparison and logic", []operator{
+			{name: LT, help: "less than", effect: comparison, examples: []string{"1 2 <"},
+				run: compare(func(x, y float64) bool { return x < y })},
+			{name: LTOREQ, help: "less than or equal to", effect: comparison, examples: []string{"2 2 <="},
+				run: compare(func(x, y float64) bool { return x <= y })},
+			{name: EQ, help: "equal to", effect: equality, examples: []string{"0.5 1/2 =="},
+				run: compare(func(x, y float64) bool { return x == y })},
+			{name: NOTEQ, help: "not equal to", effect: equality, examples: []string{"1 2 !="},
+				run: compare(func(x, y float64) bool { return x != y })},
+			{name: GT, help: "greater than", effect: comparison, examples: []string{"1 2 >"},
+				run: compare(func(x, y float64) bool { return x > y })},
+			{name: GTOREQ, help: "greater than or equal to", effect: comparison, examples: []string{"1 2 >="},
+				run: compare(func(x, y float64) bool { return x >= y })},
+			{name: NOT, aliases: []string{"not"}, help: "not", effect: unaryBool, examples: []string{"1 2 < !"},
+				run: (*Calculator).not},
+			{name: BOOLAND, aliases: []string{"and"}, help: "bool and", effect: binaryBool,
+				examples: []string{"1 2 < 2 1 < &&"}, run: logical(func(x, y bool) bool { return x && y })},
+			{name: BOOLOR, aliases: []string{"or"}, help: "bool or", effect: binaryBool,
+				examples: []string{"1 2 < 2 1 < ||"}, run: logical(func(x, y bool) bool { return x || y })},
+			{name: BOOLXOR, aliases: []string{"xor"}, help: "bool xor", effect: binaryBool,
+				examples: []string{"1 2 < 1 2 < ^^"}, run: logical(func(x, y bool) bool { return x != y })},
+		}},
+		{"Bits", []operator{
+			{name: BITAND, help: "bit and", effect: binaryNumber, examples: []string{"12 10 &"},
+				run: bitwise(func(x, y int64) int64 { return x & y })},
+			{name: BITOR, help: "bit or", effect: binaryNumber, examples: []string{"12 10 |"},
+				run: bitwise(func(x, y int64) int64 { return x | y })},
+			{name: BITXOR, help: "bit xor", effect: binaryNumber, examples: []string{"12 10 ^"},
+				run: bitwise(func(x, y int64) int64 { return x ^ y })},
+			{name: BITNOT, help: "clear the bits of a number that are set in the number on top of it", effect: binaryNumber,
+				examples: []string{"12 10 ~"}, run: bitwise(func(x, y int64) int64 { return x &^ y })},
+			{name: BITLEFT, help: "bit shift left", effect: binaryNumber, examples: []string{"1 4 <<"},
+				run: shiftBits(func(x int64, n uint64) int64 { return x << n })},
+			{name: BITRIGHT, help: "bit shift right", effect: binaryNumber, examples: []string{"16 2 >>"},
+				run: shiftBits(func(x int64, n uint64) int64 { return x >> n })},
+		}},
+		{"Trigonometry", []operator{
+			{name: SIN, help: "sine", effect: unaryComplex, examples: []string{"pi 2 / sin"}, run: unary(math.Sin)},
+			{name: COS, help: "cosine", effect: unaryComplex, examples: []string{"0 cos"}, run: unary(math.Cos)},
+			{name: ASIN, help: "inverse sine", effect: unaryComplex, examples: []string{"1 asin"}, run: unary(math.Asin)},
+			{name: ACOS, help: "inverse cosine", effect: unaryComplex, examples: []string{"1 acos"}, run: unary(math.Acos)},
+			{name: ATAN, help: "inverse tangent", effect: unaryComplex, examples: []string{"1 atan"}, run: unary(math.Atan)},
+			{name: SINH, help: "hyperbolic sine", effect: unaryComplex, examples: []string{"0 sinh"}, run: unary(math.Sinh)},
+			{name: COSH, help: "hyperbolic cosine", effect: unaryComplex, examples: []string{"0 cosh"},
+				run: unary(math.Cosh)},
+			{name: TANH, help: "hyperbolic tangent", effect: unaryComplex, examples: []string{"0 tanh"},
+				run: unary(math.Tanh)},
+		}},
+		{"Modes", []operator{
+			{name: DEC, help: "dec mode", effect: noEffect, run: (*Calculator).setMode},
+			{name: HEX, help: "hex mode", effect: noEffect, examples: []string{"hex ff 1 +"}, run: (*Calculator).setMode},
+			{name: BIN, help: "bin mode", effect: noEffect, examples: []string{"bin 101 1 +"}, run: (*Calculator).setMode},
+			{name: OCT, help: "oct mode", effect: noEffect, examples: []string{"oct 17 1 +"}, run: (*Calculator).setMode},
+			{name: DECIMAL, help: "decimal mode", effect: noEffect, examples: []string{"decimal 0.1 0.2 +"}, run: (*Calculator).setMode},
+			{name: SIGFIG, help: "significant figures mode", effect: noEffect, examples: []string{"sigfig 2.50 3.1 *"},
+				run: (*Calculator).setMode},
+		}},
+		{"Complex numbers", []operator{
+			{name: RTOC, help: "make a complex number from its real and imaginary parts", effect: makeComplex,
+				examples: []string{"3 4 r->c"}},
+			{name: CTOR, help: "split a complex number into its real and imaginary parts",
+				effect: complexParts, examples: []string{"3+4i c->r"}},
+			{name: POLAR, help: "split a complex number into its magnitude and angle",
+				effect: complexParts, examples: []string{"0+2i polar"}},
+			{name: RECT, help: "make a complex number from its magnitude and angle", effect: makeComplex,
+				examples: []string{"2 0 rect"}},
+			{name: RE, help: "real part", effect: complexPart, examples: []string{"3+4i re"}},
+			{name: IM, help: "imaginary part", effect: complexPart, examples: []string{"3+4i im"}},
+			{name: ARG, help: "angle of a complex number", effect: complexPart, examples: []string{"0+1i arg"}},
+			{name: CONJ, help: "complex conjugate", effect: unaryComplex, examples: []string{"3+4i conj"}},
+			{name: PROMOTE, help: "toggle giving complex numbers instead of NaN", effect: noEffect, impure: true,
+				examples: []string{"complex -1 sqrt"}, run: (*Calculator).togglePromote},
+		}},
+		{"Intervals and uncertainties", []operator{
+			{name: LO, help: "lower bound of an interval", effect: unaryNumber, examples: []string{"[1.9,2.1] lo"}},
+			{name: HI, help: "upper bound of an interval", effect: unaryNumber, examples: []string{"[1.9,2.1] hi"}},
+			{name: MID, help: "midpoint of an interval", effect: unaryNumber, examples: []string{"[1,2] mid"}},
+			{name: WIDTH, help: "width of an interval", effect: unaryNumber, examples: []string{"[1,2] width"}},
+			{name: PLUSMINUS, aliases: []string{"+/-"}, help: "attach an uncertainty to a value",
+				effect: &effect{in: []Kind{Number, Number}, out: []Kind{Measurement}}, impure: true,
+				examples: []string{"9.81 0.02 ± 2 *"}},
+		}},
+		{"Vectors", []operator{
+			{name: TOVECTOR, help: "pack n items into a vector", examples: []string{"1 2 3 3 ->v"}},
+			{name: FROMVECTOR, help: "unpack a vector onto the stack followed by its length",
+				examples: []string{"[1 2 3] v->"}},
+			{name: LEN, help: "length of a vector, string or list", effect: &effect{in: []Kind{Any}, out: []Kind{Number}},
+				examples: []string{"[1 2 3] len"}},
+			{name: GET, help: "get the element of a vector at an index",
+				effect:   &effect{in: []Kind{Vector, Number}, out: []Kind{Number}},
+				examples: []string{"[1 2 3] 0 get"}},
+			{name: PUT, help: "replace the element of a vector at an index",
+				effect: &effect{in: []Kind{Vector, Number, Any}, out: []Kind{Vector}}, examples: []string{"[1 2 3] 0 9 put"}},
+			{name: SUM, help: "sum of the elements of a vector", effect: &effect{in: []Kind{Vector}, out: []Kind{Number}},
+				examples: []string{"[1 2 3] sum"}},
+			{name: DOT, help: "dot product", effect: &effect{in: []Kind{Vector, Vector}, out: []Kind{Number}},
+				examples: []string{"[1 2 3] [4 5 6] dot"}},
+			{name: CROSS, help: "cross product", effect: &effect{in: []Kind{Vector, Vector}, out: []Kind{Vector}},
+				examples: []string{"[1 0 0] [0 1 0] cross"}},
+			{name: NORM, help: "length of a vector as a distance", effect: &effect{in: []Kind{Vector}, out: []Kind{Number}},
+				examples: []string{"[3 4] norm"}},
+			{name: UNIT, help: "vector of length 1 in the same direction",
+				effect: &effect{in: []Kind{Vector}, out: []Kind{Vector}}, examples: []string{"[3 4] unit"}},
+		}},
+		{"Matrices", []operator{
+			{name: ELEMMUL, help: "multiply element by element", effect: binaryComplex,
+				examples: []string{"[[1 2] [3 4]] [[5 6] [7 8]] .*"}},
+			{name: TRANSPOSE, help: "transpose a matrix", effect: &effect{in: []Kind{Matrix}, out: []Kind{Matrix}},
+				examples: []string{"[[1 2] [3 4]] transpose"}},
+			{name: DET, help: "determinant", effect: &effect{in: []Kind{Matrix}, out: []Kind{Number}},
+				examples: []string{"[[1 2] [3 4]] det"}},
+			{name: INV, help: "inverse of a matrix", effect: &effect{in: []Kind{Matrix}, out: []Kind{Matrix}},
+				examples: []string{"[[1 2] [3 4]] inv"}},
+			{name: RANK, help: "rank of a matrix", effect: &effect{in: []Kind{Matrix}, out: []Kind{Number}},
+				examples: []string{"[[1 2] [2 4]] rank"}},
+			{name: TRACE, help: "sum of the diagonal of a matrix", effect: &effect{in: []Kind{Matrix}, out: []Kind{Number}},
+				examples: []string{"[[1 2] [3 4]] trace"}},
+			{name: IDENTITY, help: "n by n identity matrix", effect: &effect{in: []Kind{Number}, out: []Kind{Matrix}},
+				examples: []string{"2 identity"}},
+			{name: LU, help: "LU decomposition with partial pivoting, pushing L, U and P where PA = LU",
+				effect:   &effect{in: []Kind{Matrix}, out: []Kind{Matrix, Matrix, Matrix}},
+				examples: []string{"[[1 2] [3 4]] lu"}},
+			{name: SOLVE, help: "solve the linear system Ax = b, where b is a vector or a matrix",
+				effect: &effect{in: []Kind{Matrix, Any}, out: []Kind{Any}}, examples: []string{"[[2 0] [0 4]] [2 8] solve"}},
+		}},
+		{"Polynomials", []operator{
+			{name: TOPOLY, help: "make a polynomial from a vector of coefficients, highest power first",
+				effect: &effect{in: []Kind{Vector}, out: []Kind{Polynomial}}, examples: []string{"[1 -3 2] ->p"}},
+			{name: PEVAL, help: "evaluate a polynomial at x",
+				effect:   &effect{in: []Kind{Polynomial, Number}, out: []Kind{Number}},
+				examples: []string{"p[1 -3 2] 3 peval"}},
+			{name: PDIV, help: "divide polynomials, pushing the quotient and remainder",
+				effect:   &effect{in: []Kind{Polynomial, Polynomial}, out: []Kind{Polynomial, Polynomial}},
+				examples: []string{"p[1 0 1] p[1 1] pdiv"}},
+			{name: PDERIV, help: "derivative of a polynomial", effect: &effect{in: []Kind{Polynomial}, out: []Kind{Polynomial}},
+				examples: []string{"p[1 -3 2] pderiv"}},
+			{name: PINTEG, help: "integral of a polynomial", effect: &effect{in: []Kind{Polynomial}, out: []Kind{Polynomial}},
+				examples: []string{"p[3 2] pinteg"}},
+			{name: PROOTS, help: "vector of the real and complex roots of a polynomial",
+				effect: &effect{in: []Kind{Polynomial}, out: []Kind{Vector}}, examples: []string{"p[1 -3 2] proots"}},
+		}},
+		{"Strings", []operator{
+			{name: CONCAT, help: "join two strings, showing other items as they are shown on the stack",
+				effect: &effect{in: []Kind{Any, Any}, out: []Kind{String}}, impure: true,
+				examples: []string{`"x = " 1.5 concat`}},
+			{name: SUBSTR, help: "the n characters of a string from an index",
+				effect:   &effect{in: []Kind{String, Number, Number}, out: []Kind{String}},
+				examples: []string{`"hello" 1 3 substr`}},
+			{name: UPPER, help: "string in upper case", effect: &effect{in: []Kind{String}, out: []Kind{String}},
+				examples: []string{`"hello" upper`}},
+			{name: LOWER, help: "string in lower case", effect: &effect{in: []Kind{String}, out: []Kind{String}},
+				examples: []string{`"HELLO" lower`}},
+			{name: SPLIT, help: "split a string into a vector of strings at a separator",
+				effect: &effect{in: []Kind{String, String}, out: []Kind{Vector}}, examples: []string{`"a,b" "," split`}},
+			{name: TOSTR, help: "show an item as a string", effect: &effect{in: []Kind{Any}, out: []Kind{String}},
+				impure: true, examples: []string{"3/2 ->str"}},
+			{name: FROMSTR, help: "parse a number from a string in the current mode",
+				effect: &effect{in: []Kind{String}, out: []Kind{Number}}, impure: true, examples: []string{`"42" str->`}},
+			{name: FORMAT, help: "fill in the printf-style placeholders of a string with items from the stack",
+				examples: []string{`3 2 / pi "%v is about %.2f" format`}},
+		}},
+		{"Lists", []operator{
+			{name: TOLIST, help: "pack n items into a list", examples: []string{`1 "two" 2 ->list`}},
+			{name: FROMLIST, help: "unpack a list onto the stack followed by its length", examples: []string{"{ 1 2 } list->"}},
+			{name: MAP, help: "run a block on each element of a list, collecting what it leaves into a list",
+				effect: &effect{in: []Kind{List, List}, out: []Kind{List}}, impure: true,
+				examples: []string{"{ 1 2 3 } { dup * } map"}},
+			{name: FILTER, help: "keep the elements of a list for which a block leaves true",
+				effect: &effect{in: []Kind{List, List}, out: []Kind{List}}, impure: true,
+				examples: []string{"0 10 range { 2 % 0 == } filter"}},
+			{name: REDUCE, help: "combine the elements of a list with a block, starting from the first",
+				effect: &effect{in: []Kind{List, List}, out: []Kind{Any}}, impure: true,
+				examples: []string{"{ 1 2 3 } { + } reduce"}},
+			{name: FOLD, help: "combine the elements of a list with a block, starting from an initial value",
+				effect: &effect{in: []Kind{List, Any, List}, out: []Kind{Any}}, impure: true,
+				examples: []string{"{ 1 2 3 } 10 { + } fold"}},
+			{name: EACH, help: "run a block on each element of a list, leaving what it leaves on the stack",
+				examples: []string{"{ 1 2 3 } { 10 * } each"}},
+			{name: ZIP, help: "pair up the elements of two lists", effect: &effect{in: []Kind{List, List}, out: []Kind{List}},
+				examples: []string{`{ 1 2 } { "a" "b" } zip`}},
+			{name: SORT, help: "sort a list, numbers before strings", effect: &effect{in: []Kind{List}, out: []Kind{List}},
+				examples: []string{`{ "b" 3 1 } sort`}},
+			{name: REVERSE, help: "reverse a list", effect: &effect{in: []Kind{List}, out: []Kind{List}},
+				examples: []string{"{ 1 2 3 } reverse"}},
+			{name: RANGE, help: "list of the integers from a up to but not including b",
+				effect: &effect{in: []Kind{Number, Number}, out: []Kind{List}}, examples: []string{"1 5 range"}},
+		}},
+		{"Decimals and significant figures", []operator{
+			{name: SCALE, help: "set the number of decimal places in decimal mode", effect: &effect{in: []Kind{Number}},
+				impure: true, examples: []string{"decimal 4 scale 1 3 /"}, run: (*Calculator).setScale},
+			{name: "round-half-even", argument: "half-even", help: "round decimal results to the nearest, ties to even",
+				effect: noEffect, impure: true, examples: []string{"decimal 1 scale 0.25 1 *"}, run: (*Calculator).setRounding},
+			{name: "round-half-up", argument: "half-up", help: "round decimal results to the nearest, ties away from zero",
+				effect: noEffect, impure: true, examples: []string{"decimal 1 scale round-half-up 0.25 1 *"}, run: (*Calculator).setRounding},
+			{name: "round-down", argument: "down", help: "round decimal results towards zero", effect: noEffect,
+				impure: true, examples: []string{"decimal 0 scale round-down 2 3 /"}, run: (*Calculator).setRounding},
+			{name: "round-ceiling", argument: "ceiling", help: "round decimal results up", effect: noEffect, impure: true,
+				examples: []string{"decimal 0 scale round-ceiling 1 3 /"}, run: (*Calculator).setRounding},
+			{name: SIGFIGS, help: "number of significant figures", effect: unaryNumber,
+				examples: []string{"sigfig 2.50 sigfigs"}},
+		}},
+		{"Stack", []operator{
+			{name: DUP, help: "duplicate top stack item", examples: []string{"3 dup"}, run: (*Calculator).dup},
+			{name: DUPN, help: "duplicate top n stack items in order", examples: []string{"1 2 2 dupn"}, run: (*Calculator).dupN},
+			{name: DROP, help: "drop top item from the stack", effect: &effect{in: []Kind{Any}}, examples: []string{"1 2 drop"},
+				run: (*Calculator).drop},
+			{name: DROPN, help: "drop n items from the stack", examples: []string{"1 2 3 1 dropn"}, run: (*Calculator).dropN},
+			{name: SWAP, help: "swap top 2 stack items", examples: []string{"1 2 swap"}, run: (*Calculator).swap},
+			{name: ROLL, help: "roll stack upwards", examples: []string{"1 2 3 roll"}, run: (*Calculator).roll},
+			{name: ROLLD, help: "roll stack downwards", examples: []string{"1 2 3 rolld"}, run: (*Calculator).rollDown},
+			{name: PICK, help: "remove the item at index n from the bottom of the stack", examples: []string{"1 2 3 1 pick"},
+				run: (*Calculator).pick},
+			{name: DEPTH, help: "push current stack depth", examples: []string{"1 2 3 depth"}, run: (*Calculator).depth},
+			{name: CLRSTACK, help: "clear stack", run: (*Calculator).clearStack},
+			{name: STACK, help: "toggle stack display from horizontal to vertical", effect: noEffect, impure: true,
+				run: (*Calculator).toggleDisplay},
+		}},
+		{"Registers", []operator{
+			{name: ASSIGN, argument: "x", help: "assign a value to the x register", examples: []string{"5 x= x x *"},
+				run: (*Calculator).assign},
+			{name: CLRVARS, help: "clear values", effect: noEffect, impure: true, run: (*Calculator).clearValues},
+			{name: CLRALL, help: "clear stack and values", run: (*Calculator).clearAll},
+		}},
+		{"Macros", []operator{
+			{name: MACRODEF, help: "define a macro"},
+			{name: DISASM, help: "print the compiled code of a macro"},
+			{name: REPEAT, help: "repeat an operation n times", examples: []string{"1 3 repeat ++"}},
+			{name: IMPORT, help: "run the script in the file named by a string, keeping its macros and registers",
+				impure: true, run: (*Calculator).importScript},
+		}},
+		{"Testing", []operator{
+			{name: ASSERT, help: "fail unless the top item is true", effect: &effect{in: []Kind{Boolean}}, impure: true,
+				run: (*Calculator).assert},
+			{name: ASSERTEQ, help: "fail unless the top 2 items are equal", effect: &effect{in: []Kind{Any, Any}},
+				impure: true, run: (*Calculator).assertEqual},
+			{name: ASSERTNEAR, help: "fail unless the top 2 items are equal within the tolerance on top of them",
+				effect: &effect{in: []Kind{Number, Number, Number}}, impure: true, run: (*Calculator).assertNear},
+		}},
+		{"Session", []operator{
+			{name: EXIT, aliases: []string{"quit"}, help: "exit", effect: noEffect, impure: true, run: (*Calculator).exit},
+		}},
+	}
+
+	for _, g := range builtins {
+		for i := range g.operators {
+			op := &g.operators[i]
+			operators[op.name] = op
+			for _, alias := range op.aliases {
+				aliases[alias] = op.name
+			}
+			if op.effect != nil {
+				effects[op.name] = *op.effect
+			}
+		}
+	}
+}
+
+// unary -> a command that replaces the number on top of the stack
+func unary(f func(x float64) float64) func(*Calculator, Token) {
+	return func(c *Calculator, token Token) {
+		x := c.popNumber(token.Type)
+		c.push(float(f(x)))
+	}
+}
+
+// binary -> a command that replaces the top two numbers, the top one being y
+func binary(f func(x, y float64) float64) func(*Calculator, Token) {
+	return func(c *Calculator, token Token) {
+		y := c.popNumber(token.Type)
+		x := c.popNumber(token.Type)
+		c.push(float(f(x, y)))
+	}
+}
+
+// bitwise -> a command that replaces the top two numbers, working on them as int64s
+func bitwise(f func(x, y int64) int64) func(*Calculator, Token) {
+	return binary(func(x, y float64) float64 {
+		return float64(f(int64(x), int64(y)))
+	})
+}
+
+// shiftBits -> a command that replaces the top two numbers, shifting the bits of one as an int64 by the other
+func shiftBits(f func(x int64, n uint64) int64) func(*Calculator, Token) {
+	return func(c *Calculator, token Token) {
+		n := c.popNumber(token.Type)
+		x := c.popNumber(token.Type)
+		if !(n >= 0) {
+			throw("Cannot shift by a negative number of bits: %v", token.Type)
+		}
+		c.push(float(f(int64(x), uint64(math.Min(n, 64)))))
+	}
+}
+
+// compare -> a command that replaces the top two numbers with how they compare
+func compare(f func(x, y float64) bool) func(*Calculator, Token) {
+	return func(c *Calculator, token Token) {
+		y := c.popNumber(token.Type)
+		x := c.popNumber(token.Type)
+		c.push(boolean(f(x, y)))
+	}
+}
+
+// logical -> a command that replaces the top two booleans
+func logical(f func(x, y bool) bool) func(*Calculator, Token) {
+	return func(c *Calculator, token Token) {
+		y := c.popBoolean(token.Type)
+		x := c.popBoolean(token.Type)
+		c.push(boolean(f(x, y)))
+	}
+}
+
+func constant(x float64) func(*Calculator, Token) {
+	return func(c *Calculator, token Token) {
+		c.push(float(x))
+	}
+}
+
+func (c *Calculator) toggleMixed(Token) {
+	c.mixed = !c.mixed
+}
+
+func (c *Calculator) togglePromote(Token) {
+	c.promote = !c.promote
+}
+
+func (c *Calculator) not(token Token) {
+	c.push(boolean(!c.popBoolean(token.Type)))
+}
+
+func sign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	case x == 0:
+		return 0
+	}
+	return x
+}
+
+func (c *Calculator) random(Token) {
+	if c.rng == nil {
+		if c.sandboxed {
+			throw("Random numbers need a seed in a sandbox: %v", RAND)
+		}
+		c.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	c.push(float(c.rng.Float64()))
+}
+
+func (c *Calculator) seed(Token) {
+	c.randomSeed, c.seeded = int64(c.popNumber(SEED)), true
+	c.rng = rand.New(rand.NewSource(c.randomSeed))
+}
+
+func (c *Calculator) setMode(token Token) {
+	c.mode = token.Type
+}
+
+func (c *Calculator) setPrecision(Token) {
+	digits := c.popNumber(PREC)
+	if digits < 0 {
+		digits = 0
+	}
+	c.checkBits(digits * log2of10)
+	c.precision = int(digits)
+}
+
+func (c *Calculator) setScale(Token) {
+	n := c.popNumber(SCALE)
+	if n < 0 {
+		n = 0
+	}
+	c.checkBits(n * log2of10)
+	c.scale = int(n)
+}
+
+func (c *Calculator) setRounding(token Token) {
+	c.rounding = token.Argument
+}
+
+func (c *Calculator) toggleDisplay(Token) {
+	if c.display == "horizontal" {
+		c.display = "vertical"
+	} else {
+		c.display = "horizontal"
+	}
+}
+
+// Help -> the built-in commands and their aliases, for rpn --help
+func Help() string {
+	return help(builtins)
+}
+
+// Help -> the commands of this calculator and their aliases
+func (c *Calculator) Help() string {
+	return help(c.groups())
+}
+
+// groups -> the commands of this calculator as they are listed in the help, with the built-in ones it has
+// disabled left out and the ones registered with it listed last
+func (c *Calculator) groups() []group {
+	var groups []group
+	for _, g := range builtins {
+		enabled := group{title: g.title}
+		for i := range g.operators {
+			if c.operators[g.operators[i].name] == &g.operators[i] {
+				enabled.operators = append(enabled.operators, g.operators[i])
+			}
+		}
+		if len(enabled.operators) > 0 {
+			groups = append(groups, enabled)
+		}
+	}
+	if len(c.custom) > 0 {
+		custom := group{title: "Custom"}
+		for _, op := range c.custom {
+			custom.operators = append(custom.operators, *op)
+		}
+		groups = append(groups, custom)
+	}
+	return groups
+}
+
+func help(groups []group) string {
+	var b strings.Builder
+	var spellings []string
+	for _, g := range groups {
+		width := 0
+		for _, op := range g.operators {
+			if n := len([]rune(op.name)); n > width {
+				width = n
+			}
+		}
+		fmt.Fprintf(&b, "\n\t%v:\n", g.title)
+		for _, op := range g.operators {
+			fmt.Fprintf(&b, "\t%v%v = %q\n", op.name, strings.Repeat(" ", width-len([]rune(op.name))), op.help)
+			for _, alias := range op.aliases {
+				spellings = append(spellings, fmt.Sprintf("%v = %v", alias, op.name))
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n\tAliases:\n\t%v\n", strings.Join(spellings, ", "))
+	return b.String()
+}
+
+// Docs -> a Markdown reference of the built-in commands, with the result of each example worked out by running it
+func Docs() string {
+	return New().Docs()
+}
+
+// Docs -> a Markdown reference of the commands of this calculator
+func (c *Calculator) Docs() string {
+	var b strings.Builder
+	b.WriteString("# Commands\n")
+	for _, g := range c.groups() {
+		fmt.Fprintf(&b, "\n## %v\n\n", g.title)
+		for _, op := range g.operators {
+			fmt.Fprintf(&b, "- `%v`", op.name)
+			if op.effect != nil {
+				fmt.Fprintf(&b, " `( %v-- %v)`", kindList(op.effect.in), kindList(op.effect.out))
+			}
+			fmt.Fprintf(&b, ": %v.", op.help)
+			if len(op.aliases) > 0 {
+				fmt.Fprintf(&b, " Also spelled `%v`.", strings.Join(op.aliases, "`, `"))
+			}
+			for _, example := range op.examples {
+				fmt.Fprintf(&b, " `%v` gives `%v`.", example, c.fresh().runExample(example))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// kindList -> the names of the types a command pops or pushes, each followed by a space
+func kindList(kinds []Kind) string {
+	var s string
+	for _, kind := range kinds {
+		s += kind.String() + " "
+	}
+	return s
+}
+
+// fresh -> a calculator with an empty stack and the same commands as this one, optimised in the same way
+func (c *Calculator) fresh() *Calculator {
+	f := &Calculator{operators: c.operators, aliases: c.aliases, custom: c.custom, unoptimized: c.unoptimized}
+	f.reset()
+	return f
+}
+
+// runExample -> the stack left by a calculation in a fresh calculator, or the error it fails with
+func (c *Calculator) runExample(example string) string {
+	if err := protect(func() { c.eval(tokenize(example)) }); err != nil {
+		return err.Error()
+	}
+	items := make([]string, len(c.stack))
+	for i, item := range c.stack {
+		items[i] = item.show(c)
+	}
+	return strings.Join(items, " ")
+}
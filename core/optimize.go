@@ -0,0 +1,191 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// maxInline -> macros that compile to at most this many instructions are inlined into their callers
+const maxInline = 8
+
+// DisableOptimizations -> run the macros of this calculator exactly as they are written
+func (c *Calculator) DisableOptimizations() {
+	c.unoptimized = true
+	c.compiled = make(map[compiledKey][]instruction)
+}
+
+// cancelling -> pairs of commands that undo each other, with the number of items the first one needs to succeed
+var cancelling = map[[2]string]int{
+	{SWAP, SWAP}:  2,
+	{DUP, DROP}:   1,
+	{ROLL, ROLLD}: 0,
+	{ROLLD, ROLL}: 0,
+}
+
+// emit -> append a command to compiled code, folding it into the instructions before it where possible
+func (c *compiler) emit(code []instruction, in instruction) []instruction {
+	if c.calc.unoptimized || in.op != opCommand {
+		return append(code, in)
+	}
+	if n := len(code); n > 0 && c.cancels(code[n-1], in) {
+		// the pair still fails like its first command does when there aren't enough items on the stack, unless
+		// the items are pushed just before it
+		last := code[n-1]
+		depth := cancelling[[2]string{last.token.Type, in.token.Type}]
+		pushed := 0
+		for i := n - 2; i >= 0 && code[i].op == opPush && pushed < depth; i-- {
+			pushed++
+		}
+		if pushed == depth {
+			return code[:n-1]
+		}
+		return append(code[:n-1], instruction{op: opCheck, name: last.token.Type, depth: depth})
+	}
+	// folding runs commands at the current precision, which the code may have changed by now
+	if c.precision != c.calc.precision {
+		return append(code, in)
+	}
+	if folded, ok := c.calc.fold(code, in.token); ok {
+		return folded
+	}
+	return append(code, in)
+}
+
+// cancels -> whether a command undoes the one before it, which commands registered in place of the built-in ones
+// may not
+func (c *compiler) cancels(last, in instruction) bool {
+	if _, ok := cancelling[[2]string{last.token.Type, in.token.Type}]; !ok || last.op != opCommand {
+		return false
+	}
+	return !last.operator.custom && !in.operator.custom
+}
+
+// fold -> run a command on the constants before it while compiling, replacing them with its result
+func (c *Calculator) fold(code []instruction, token Token) ([]instruction, bool) {
+	if op := c.operators[token.Type]; op == nil || op.impure {
+		return nil, false
+	}
+	var arity int
+	switch token.Type {
+	case DUP:
+		arity = 1
+	case SWAP:
+		arity = 2
+	default:
+		effect, ok := effects[token.Type]
+		if !ok {
+			return nil, false
+		}
+		arity = len(effect.in)
+	}
+	if len(code) < arity {
+		return nil, false
+	}
+
+	args := make([]Value, arity)
+	for i, in := range code[len(code)-arity:] {
+		if in.op != opPush {
+			return nil, false
+		}
+		args[i] = in.value
+		// decimal results depend on the scale and rounding mode when they run
+		if in.value.Kind() == Decimal {
+			return nil, false
+		}
+	}
+	// large factorials take long enough to be worth leaving until they run
+	if token.Type == FACT {
+		if n, ok := bigValue(args[0], 64); !ok || n.Cmp(big.NewFloat(170)) > 0 {
+			return nil, false
+		}
+	}
+
+	saved := c.stack
+	c.stack = args
+	err := protect(func() { c.handleCommand(token) })
+	results := c.stack
+	c.stack = saved
+	if err != nil {
+		return nil, false
+	}
+	// whether NaN becomes a complex number depends on the promote setting when the code runs
+	for _, result := range results {
+		if number, ok := result.(float); ok && math.IsNaN(float64(number)) || result.Kind() == Complex {
+			return nil, false
+		}
+	}
+
+	code = code[:len(code)-arity]
+	for _, result := range results {
+		code = append(code, instruction{op: opPush, value: result})
+	}
+	return code, true
+}
+
+// inline -> compile the body of a small macro in place of a call to it
+func (c *compiler) inline(word string) ([]instruction, bool) {
+	body, ok := c.calc.macros[word]
+	if c.calc.unoptimized || !ok || c.inlining[word] {
+		return nil, false
+	}
+	if _, ok := c.calc.values[word]; ok {
+		return nil, false
+	}
+	for _, mode := range modes {
+		if _, err := parseValue(word, mode, 0); err == nil {
+			return nil, false
+		}
+	}
+
+	mode, digits := c.mode, c.precision
+	c.inlining[word] = true
+	code := c.compile(body)
+	delete(c.inlining, word)
+
+	small := len(code) <= maxInline
+	for _, in := range code {
+		small = small && in.op != opDefine && in.op != opEval
+	}
+	if !small {
+		c.mode, c.precision = mode, digits
+		return nil, false
+	}
+	return code, true
+}
+
+// disassemble -> print the compiled code of a macro
+func (c *Calculator) disassemble(name string) {
+	if c.pooled {
+		throw("Cannot print the compiled code of a macro in a pool: %v", DISASM)
+	}
+	if _, ok := c.macros[name]; !ok {
+		throw("Unknown macro: %v", name)
+	}
+	fmt.Printf("%v:\n", name)
+	c.printCode(c.compile(c.macros[name], c.mode, c.precision), 1)
+}
+
+func (c *Calculator) printCode(code []instruction, depth int) {
+	indent := strings.Repeat("    ", depth)
+	for i, in := range code {
+		switch in.op {
+		case opPush:
+			fmt.Printf("%v%04d push    %v\n", indent, i, in.value.show(c))
+		case opCommand:
+			fmt.Printf("%v%04d command %v\n", indent, i, in.name)
+		case opCheck:
+			fmt.Printf("%v%04d check   %v %v\n", indent, i, in.name, in.depth)
+		case opWord:
+			fmt.Printf("%v%04d word    %v\n", indent, i, in.name)
+		case opRepeat:
+			fmt.Printf("%v%04d repeat\n", indent, i)
+			c.printCode(in.body, depth+1)
+		case opDefine:
+			fmt.Printf("%v%04d define  %v %v\n", indent, i, in.name, strings.Join(in.words, " "))
+		case opEval:
+			fmt.Printf("%v%04d eval    %v\n", indent, i, strings.Join(in.words, " "))
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// pluginTimeout -> how long a plugin has to describe itself or to run one of its words
+var pluginTimeout = 5 * time.Second
+
+// pluginWord -> a word as a plugin describes it when it is run with --describe
+type pluginWord struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	Arity   int      `json:"arity"`
+	// Args -> the type of each operand by the name used in messages, such as "number", "string", "list" or "any"
+	Args     []string `json:"args"`
+	Help     string   `json:"help"`
+	Examples []string `json:"examples"`
+}
+
+// pluginDescription -> what a plugin prints when it is run with --describe
+type pluginDescription struct {
+	Words []pluginWord `json:"words"`
+}
+
+// pluginDirs -> the directories plugins are loaded from: those in RPN_PLUGIN_PATH, or ~/.config/rpn/plugins
+func pluginDirs() []string {
+	if path := os.Getenv("RPN_PLUGIN_PATH"); path != "" {
+		return filepath.SplitList(path)
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".config", "rpn", "plugins")}
+}
+
+// LoadPlugins -> register the words of every plugin executable in the plugin directories. A plugin that can't be
+// loaded is skipped, and the first such error is returned once the others are loaded.
+func (c *Calculator) LoadPlugins() error {
+	var first error
+	for _, dir := range pluginDirs() {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if !file.Mode().IsRegular() || file.Mode()&0111 == 0 {
+				continue
+			}
+			if err := c.LoadPlugin(filepath.Join(dir, file.Name())); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// LoadPlugin -> register the words of a plugin executable. The plugin is run with --describe to list its words as
+// JSON, and with the name of a word to run it, reading its operands as a JSON array and writing its results as one.
+// It fails a calculation by exiting with an error and writing the message to stderr.
+func (c *Calculator) LoadPlugin(path string) error {
+	if c.sandboxed {
+		return fmt.Errorf("%v: Cannot load plugins in a sandbox", path)
+	}
+	out, err := runPlugin(path, "--describe", nil)
+	if err != nil {
+		return fmt.Errorf("%v: %v", path, err)
+	}
+	var description pluginDescription
+	if err := json.Unmarshal(out, &description); err != nil {
+		return fmt.Errorf("%v: Invalid description: %v", path, err)
+	}
+	// every word is checked before any is registered, so that a plugin is loaded whole or not at all
+	ops := make([]Op, 0, len(description.Words))
+	names := make(map[string]bool)
+	for _, word := range description.Words {
+		op, err := word.op(path)
+		if err == nil {
+			err = c.registrable(op)
+		}
+		for _, alias := range op.Aliases {
+			if names[alias] && err == nil {
+				err = fmt.Errorf("Already a command: %v", alias)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %v", path, err)
+		}
+		names[op.Name] = true
+		ops = append(ops, op)
+	}
+	for _, op := range ops {
+		if err := c.Register(op); err != nil {
+			return fmt.Errorf("%v: %v", path, err)
+		}
+		c.plugins = append(c.plugins, op.Name)
+	}
+	return nil
+}
+
+// op -> a command that runs a word of a plugin
+func (w pluginWord) op(path string) (Op, error) {
+	var args []Kind
+	for _, name := range w.Args {
+		kind, ok := kindNamed(name)
+		if !ok {
+			return Op{}, fmt.Errorf("Unknown type %v: %v", name, w.Name)
+		}
+		args = append(args, kind)
+	}
+	return Op{Name: w.Name, Aliases: w.Aliases, Arity: w.Arity, Args: args, Help: w.Help, Examples: w.Examples,
+		Fn: func(operands []Value) ([]Value, error) {
+			return callPlugin(path, w.Name, operands)
+		}}, nil
+}
+
+// kindNamed -> the type of value with a name as it is used in messages
+func kindNamed(name string) (Kind, bool) {
+	for kind, kindName := range kindNames {
+		if kindName == name {
+			return kind, true
+		}
+	}
+	return Unknown, false
+}
+
+// callPlugin -> run a word of a plugin on its operands
+func callPlugin(path, word string, operands []Value) ([]Value, error) {
+	in := make([]interface{}, len(operands))
+	for i, operand := range operands {
+		x, err := toJSON(operand)
+		if err != nil {
+			return nil, err
+		}
+		in[i] = x
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	out, err := runPlugin(path, word, data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	decoder.UseNumber()
+	var results []interface{}
+	if err := decoder.Decode(&results); err != nil {
+		return nil, fmt.Errorf("Invalid plugin result: %v", err)
+	}
+	values := make([]Value, len(results))
+	for i, result := range results {
+		if values[i], err = fromJSON(result); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// runPlugin -> run a plugin with an argument and its input, giving up after the timeout. The plugin is killed then,
+// without waiting for any processes it started, which may hold on to its output.
+func runPlugin(path, arg string, input []byte) ([]byte, error) {
+	cmd := exec.Command(path, arg)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Plugin failed: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	timer := time.NewTimer(pluginTimeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case err = <-done:
+	case <-timer.C:
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("Plugin timed out after %v", pluginTimeout)
+	}
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%v", message)
+		}
+		return nil, fmt.Errorf("Plugin failed: %v", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// toJSON -> an item as it is passed to a plugin: real numbers as numbers, exactly for integers, strings as strings,
+// booleans as booleans and lists and vectors as arrays
+func toJSON(item Value) (interface{}, error) {
+	switch x := item.(type) {
+	case integer:
+		return json.Number(x.value.String()), nil
+	case text:
+		return string(x), nil
+	case boolean:
+		return bool(x), nil
+	case list:
+		return toJSONArray(x)
+	case vector:
+		return toJSONArray(x)
+	}
+	if number, ok := numberValue(item); ok && !math.IsNaN(number) && !math.IsInf(number, 0) {
+		return number, nil
+	}
+	return nil, fmt.Errorf("Cannot pass %v to a plugin", item.Kind().article())
+}
+
+func toJSONArray(items []Value) (interface{}, error) {
+	array := make([]interface{}, len(items))
+	for i, item := range items {
+		x, err := toJSON(item)
+		if err != nil {
+			return nil, err
+		}
+		array[i] = x
+	}
+	return array, nil
+}
+
+// fromJSON -> an item returned by a plugin: whole numbers as integers, other numbers as float64s, strings,
+// booleans and arrays as lists
+func fromJSON(x interface{}) (Value, error) {
+	switch x := x.(type) {
+	case json.Number:
+		if n, ok := new(big.Int).SetString(x.String(), 10); ok {
+			return integer{n}, nil
+		}
+		number, err := x.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("Invalid plugin result: %v", x)
+		}
+		return float(number), nil
+	case string:
+		return text(x), nil
+	case bool:
+		return boolean(x), nil
+	case []interface{}:
+		elements := make(list, len(x))
+		for i, element := range x {
+			value, err := fromJSON(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return elements, nil
+	}
+	return nil, fmt.Errorf("Invalid plugin result: %v", x)
+}
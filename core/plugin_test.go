@@ -0,0 +1,120 @@
+package core
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// plugin -> the path of a plugin in testdata
+func plugin(name string) string {
+	return filepath.Join("testdata", "plugins", name)
+}
+
+func TestLoadPlugin(t *testing.T) {
+	c := New()
+	if err := c.LoadPlugin(plugin("calc")); err != nil {
+		t.Fatal(err)
+	}
+	timeout := pluginTimeout
+	pluginTimeout = 200 * time.Millisecond
+	defer func() { pluginTimeout = timeout }()
+
+	tests := []resultTest{
+		// integers pass through exactly however large they are
+		{"2 64 pow 1 + echo", "{ 18446744073709551617 }"},
+		{"2 64 pow 1 + same", "{ 18446744073709551617 }"},
+		{"1/2 echo", "{ 0.5 }"},
+		{`{ 1 "a" { 2.5 } } echo`, `{ { 1 "a" { 2.5 } } }`},
+		{"1 1 == echo", "{ true }"},
+		{"[1 2] echo", "{ { 1 2 } }"},
+		{"results", `{ 18446744073709551617 0.5 "tea" true { 1 { 2 } } }`},
+		{"3+4i echo", "error: Cannot pass a complex number to a plugin: echo"},
+		{"echo", "error: Not enough items on the stack to perform this command: echo"},
+		{"fail", "error: Out of stock: fail"},
+		{"crash", "error: Plugin failed: exit status 3: crash"},
+		{"garbage", "error: Invalid plugin result: invalid character 'o' in literal null (expecting 'u'): garbage"},
+		{"hang", "error: Plugin timed out after 200ms: hang"},
+	}
+	for _, test := range tests {
+		c.stack = nil
+		start := time.Now()
+		if got := evalShown(c, test.line); got != test.expected {
+			t.Errorf("%q: got %v, expected %v", test.line, got, test.expected)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%q: took %v", test.line, elapsed)
+		}
+	}
+	if !listed(c.Help(), "echo") {
+		t.Error("expected the words of the plugin in the help")
+	}
+}
+
+func TestLoadPluginErrors(t *testing.T) {
+	tests := []struct {
+		plugin string
+		err    string
+	}{
+		{"broken", "Missing configuration"},
+		{"invalid", "Invalid description: invalid character 'w' looking for beginning of value"},
+		{"types", "Unknown type banana: first"},
+		{"partial", `Invalid command name: "two words"`},
+		{"clash", "Already a command: first"},
+		{"missing", "Plugin failed: fork/exec " + plugin("missing") + ": no such file or directory"},
+	}
+	for _, test := range tests {
+		c := New()
+		err := c.LoadPlugin(plugin(test.plugin))
+		if expected := plugin(test.plugin) + ": " + test.err; err == nil || err.Error() != expected {
+			t.Errorf("%v: got %v, expected %v", test.plugin, err, expected)
+		}
+		// none of the words of a plugin that fails to load are registered
+		if _, ok := c.keyword("first"); ok {
+			t.Errorf("%v: registered first", test.plugin)
+		}
+	}
+}
+
+// TestLoadPlugins -> the plugins that load are registered, and the first error is returned
+func TestLoadPlugins(t *testing.T) {
+	path := os.Getenv("RPN_PLUGIN_PATH")
+	defer os.Setenv("RPN_PLUGIN_PATH", path)
+	os.Setenv("RPN_PLUGIN_PATH", filepath.Join("testdata", "plugins"))
+
+	c := New()
+	if err := c.LoadPlugins(); err == nil || !strings.HasPrefix(err.Error(), plugin("broken")+": ") {
+		t.Errorf("got %v, expected the error of the broken plugin", err)
+	}
+	if _, ok := c.keyword("echo"); !ok {
+		t.Error("expected the words of the calc plugin")
+	}
+}
+
+// TestShopPlugin -> the example plugin in the repository
+func TestShopPlugin(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("the example plugin needs python3")
+	}
+	c := New()
+	if err := c.LoadPlugin(filepath.Join("..", "examples", "plugins", "shop")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []resultTest{
+		{"100 vat", "{ 120 }"},
+		{"80 25 discount", "{ 60 }"},
+		{"{ 1.5 2.5 3 } total", "{ 7 }"},
+		{`"tea" 2.5 label`, `{ "tea: 2.50" }`},
+		{"80 120 discount", "error: The discount must be between 0 and 100: discount"},
+		{"1 2 label", "error: Expected a string on the stack but found an integer"},
+	}
+	for _, test := range tests {
+		c.stack = nil
+		if got := evalShown(c, test.line); got != test.expected {
+			t.Errorf("%q: got %v, expected %v", test.line, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"math/cmplx"
+	"sort"
+	"strings"
+)
+
+// polynomial -> the coefficients of a polynomial, constant term first
+type polynomial []Value
+
+// parsePolynomial -> parse a polynomial literal such as p[1 -3 2], which lists its coefficients highest power first
+func parsePolynomial(item, mode string, digits int) (Value, bool) {
+	if !strings.HasPrefix(item, "p[") {
+		return nil, false
+	}
+	coefficients, ok := parseVector(item[1:], mode, digits)
+	if !ok || coefficients.Kind() != Vector {
+		return nil, false
+	}
+	return trim(reversed(coefficients.(vector))), true
+}
+
+func (p polynomial) Kind() Kind {
+	return Polynomial
+}
+
+func (p polynomial) show(c *Calculator) string {
+	return "p" + vector(reversed(p)).show(c)
+}
+
+func (p polynomial) Equal(other Value) bool {
+	q, ok := other.(polynomial)
+	return ok && vector(p).Equal(vector(q))
+}
+
+// handlePolynomial -> run the polynomial commands, and add, subtract and multiply polynomials and numbers
+func (c *Calculator) handlePolynomial(token Token) bool {
+	switch token.Type {
+	case TOPOLY:
+		item, err := c.pop()
+		if err != nil {
+			throwNotEnoughElementsError(TOPOLY)
+		}
+		coefficients, ok := item.(vector)
+		if !ok {
+			throwWrongElementType(Vector, item.Kind())
+		}
+		c.pushPolynomial(reversed(coefficients))
+	case PEVAL:
+		x, err := c.pop()
+		if err != nil {
+			throwNotEnoughElementsError(PEVAL)
+		}
+		p := c.popPolynomial(PEVAL)
+		// Horner's method
+		result := zero()
+		for i := len(p) - 1; i >= 0; i-- {
+			result = c.compute(PLUS, c.compute(MULTIPLY, result, x), p[i])
+		}
+		c.push(result)
+	case PDIV:
+		op1 := c.popPolynomial(PDIV)
+		op2 := c.popPolynomial(PDIV)
+		quotient, remainder := c.divide(op2, op1)
+		c.pushPolynomial(quotient)
+		c.pushPolynomial(remainder)
+	case PDERIV:
+		p := c.popPolynomial(PDERIV)
+		result := make(polynomial, 0, len(p))
+		for i := 1; i < len(p); i++ {
+			result = append(result, c.compute(MULTIPLY, p[i], integerValue(i)))
+		}
+		c.pushPolynomial(result)
+	case PINTEG:
+		p := c.popPolynomial(PINTEG)
+		result := polynomial{zero()}
+		for i := range p {
+			result = append(result, c.compute(DIVIDE, p[i], integerValue(i+1)))
+		}
+		c.pushPolynomial(result)
+	case PROOTS:
+		c.pushVector(c.roots(c.popPolynomial(PROOTS)))
+	case PLUS, MINUS, MULTIPLY:
+		if !c.anyOperand(token.Type, Polynomial) {
+			return false
+		}
+		op1 := c.popPolynomial(token.Type)
+		op2 := c.popPolynomial(token.Type)
+		if token.Type == MULTIPLY {
+			c.pushPolynomial(c.multiply(op2, op1))
+		} else {
+			c.pushPolynomial(c.add(op2, op1, token.Type))
+		}
+	case EQ, NOTEQ:
+		if !c.anyOperand(token.Type, Polynomial) {
+			return false
+		}
+		op1, _ := c.pop()
+		op2, _ := c.pop()
+		c.push(boolean(op2.Equal(op1) == (token.Type == EQ)))
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *Calculator) add(a, b polynomial, command string) polynomial {
+	length := len(a)
+	if len(b) > length {
+		length = len(b)
+	}
+	result := make(polynomial, length)
+	for i := range result {
+		x, y := zero(), zero()
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		result[i] = c.compute(command, x, y)
+	}
+	return result
+}
+
+func (c *Calculator) multiply(a, b polynomial) polynomial {
+	if len(a) == 0 || len(b) == 0 {
+		return polynomial{}
+	}
+	result := make(polynomial, len(a)+len(b)-1)
+	for i := range result {
+		result[i] = zero()
+	}
+	for i := range a {
+		c.checkDone()
+		for j := range b {
+			result[i+j] = c.compute(PLUS, result[i+j], c.compute(MULTIPLY, a[i], b[j]))
+		}
+	}
+	return result
+}
+
+// divide -> long division of polynomials, giving the quotient and remainder
+func (c *Calculator) divide(a, b polynomial) (polynomial, polynomial) {
+	b = trim(b)
+	if len(b) == 0 {
+		throwDivisionByZeroError(PDIV)
+	}
+	remainder := append(polynomial(nil), trim(a)...)
+	if len(remainder) < len(b) {
+		return polynomial{}, remainder
+	}
+	quotient := make(polynomial, len(remainder)-len(b)+1)
+	for i := len(quotient) - 1; i >= 0; i-- {
+		c.checkDone()
+		factor := c.compute(DIVIDE, remainder[i+len(b)-1], b[len(b)-1])
+		quotient[i] = factor
+		for j := range b {
+			remainder[i+j] = c.compute(MINUS, remainder[i+j], c.compute(MULTIPLY, factor, b[j]))
+		}
+	}
+	return quotient, trim(remainder[:len(b)-1])
+}
+
+// roots -> all the roots of a polynomial, found with the Durand-Kerner method. Roots that are real within
+// rounding error are given as real numbers, and the roots at zero of a polynomial without a constant term are
+// given exactly.
+func (c *Calculator) roots(p polynomial) []Value {
+	p = trim(p)
+	if len(p) < 2 {
+		return []Value{}
+	}
+	if zeros := 0; isZero(p[0]) {
+		for isZero(p[zeros]) {
+			zeros++
+		}
+		result := c.roots(p[zeros:])
+		i := sort.Search(len(result), func(i int) bool {
+			root := complexValue(result[i], PROOTS)
+			return real(root) > 0 || real(root) == 0 && imag(root) > 0
+		})
+		roots := append([]Value(nil), result[:i]...)
+		for ; zeros > 0; zeros-- {
+			roots = append(roots, zero())
+		}
+		return append(roots, result[i:]...)
+	}
+	degree := len(p) - 1
+	result := make([]Value, 0, degree)
+	if degree == 1 {
+		return append(result, c.compute(DIVIDE, c.compute(MINUS, zero(), p[0]), p[1]))
+	}
+
+	coefficients := make([]complex128, len(p))
+	for i, x := range p {
+		coefficients[i] = complexValue(x, PROOTS)
+	}
+	lead := coefficients[degree]
+	for i := range coefficients {
+		coefficients[i] /= lead
+	}
+	evaluate := func(x complex128) complex128 {
+		y := complex(0, 0)
+		for i := degree; i >= 0; i-- {
+			y = y*x + coefficients[i]
+		}
+		return y
+	}
+
+	z := make([]complex128, degree)
+	for i := range z {
+		z[i] = cmplx.Pow(complex(0.4, 0.9), complex(float64(i), 0))
+	}
+	for iteration := 0; iteration < 1000; iteration++ {
+		c.checkDone()
+		change := 0.0
+		for i := range z {
+			denominator := complex(1, 0)
+			for j := range z {
+				if i != j {
+					denominator *= z[i] - z[j]
+				}
+			}
+			step := evaluate(z[i]) / denominator
+			if cmplx.IsNaN(step) || cmplx.IsInf(step) {
+				// two estimates have met at a repeated root
+				continue
+			}
+			z[i] -= step
+			change = math.Max(change, cmplx.Abs(step))
+		}
+		if change < 1e-15 {
+			break
+		}
+	}
+
+	// polish each root with a few steps of Newton's method, then sort them by their real parts
+	slope := func(x complex128) complex128 {
+		y := complex(0, 0)
+		for i := degree; i >= 1; i-- {
+			y = y*x + coefficients[i]*complex(float64(i), 0)
+		}
+		return y
+	}
+	for i := range z {
+		for step := 0; step < 3; step++ {
+			next := z[i] - evaluate(z[i])/slope(z[i])
+			if cmplx.IsNaN(next) || cmplx.IsInf(next) || cmplx.Abs(evaluate(next)) >= cmplx.Abs(evaluate(z[i])) {
+				break
+			}
+			z[i] = next
+		}
+	}
+	sort.Slice(z, func(i, j int) bool {
+		if real(z[i]) != real(z[j]) {
+			return real(z[i]) < real(z[j])
+		}
+		return imag(z[i]) < imag(z[j])
+	})
+
+	for _, root := range z {
+		if math.Abs(imag(root)) < 1e-9*math.Max(1, cmplx.Abs(root)) {
+			result = append(result, float(real(root)))
+		} else {
+			result = append(result, complexNumber(root))
+		}
+	}
+	return result
+}
+
+// trim -> drop zero coefficients of the highest powers
+func trim(p polynomial) polynomial {
+	for len(p) > 0 && isZero(p[len(p)-1]) {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+func reversed(elements []Value) []Value {
+	result := make([]Value, len(elements))
+	for i, element := range elements {
+		result[len(elements)-1-i] = element
+	}
+	return result
+}
+
+func complexValue(item Value, command string) complex128 {
+	if c, ok := item.(complexNumber); ok {
+		return complex128(c)
+	}
+	number, ok := numberValue(item)
+	if !ok {
+		throw("Expected a number as a coefficient but found %v: %v", item.Kind().article(), command)
+	}
+	return complex(number, 0)
+}
+
+func zero() Value {
+	return integerValue(0)
+}
+
+func integerValue(n int) Value {
+	return integer{big.NewInt(int64(n))}
+}
+
+func (c *Calculator) pushPolynomial(p polynomial) {
+	c.push(trim(p))
+}
+
+// popPolynomial -> pop a polynomial, or a number as a constant polynomial
+func (c *Calculator) popPolynomial(command string) polynomial {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	switch item.Kind() {
+	case Polynomial:
+		return item.(polynomial)
+	case Vector, Matrix, Boolean:
+		throw("Expected a polynomial on the stack but found %v: %v", item.Kind().article(), command)
+	}
+	return polynomial{item}
+}
@@ -0,0 +1,29 @@
+package core
+
+import "testing"
+
+func TestPolynomial(t *testing.T) {
+	testResults(t, []resultTest{
+		{"[1 -3 2] ->p", "p[1 -3 2]"},
+		{"p[1 -3 2] 3 peval", "2"},
+		{"p[1 -3 2] 1/2 peval", "3/4"},
+		{"p[1 1] p[1 -1] +", "p[2 0]"},
+		{"p[1 -3 2] p[1 -3 2] -", "p[]"},
+		{"p[1 1] p[1 -1] *", "p[1 0 -1]"},
+		{"p[1 2 3] 2 *", "p[2 4 6]"},
+		{"p[1 2 3] pderiv", "p[2 2]"},
+		{"p[3 2 1] pinteg", "p[1 1 1 0]"},
+
+		// division pushes the quotient and the remainder
+		{"p[1 -3 2] p[1 -1] pdiv", "p[1 -2], p[]"},
+		{"p[1 0 1] p[1 1] pdiv", "p[1 -1], p[2]"},
+		{"p[1 -1] 0 pdiv", "error: Division by zero: pdiv"},
+
+		// roots are exact for linear and quadratic polynomials, and for roots at zero
+		{"p[2 -3] proots", "[3/2]"},
+		{"p[1 -3 2] proots", "[1 2]"},
+		{"p[1 0 1] proots", "[0-1i 0+1i]"},
+		{"p[1 0 -4 0] proots", "[-2 0 2]"},
+		{"p[1 -6 11 -6] proots v-> drop 3 1e-9 assert~ 2 1e-9 assert~ 1 1e-9 assert~", ""},
+	})
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// Pool -> calculators that evaluate independent lines in parallel, each line starting from the stack, registers,
+// macros, settings and commands of a base calculator. The base calculator must not change while the pool is in use,
+// and the commands registered with it must be safe to run from more than one goroutine at a time.
+type Pool struct {
+	base    *Calculator
+	workers int
+	// Limits -> the limits of the calculation of each line
+	Limits Limits
+}
+
+// Result -> what a line of a batch left on the stack, with its result as Calculate prints it, or why it failed
+type Result struct {
+	Stack []Value
+	// Text -> the item at the bottom of the stack as Calculate prints it, or empty if the stack is empty
+	Text string
+	Err  error
+}
+
+// NewPool -> a pool of workers evaluating lines from the state of a calculator, or one for each CPU if workers
+// isn't positive
+func NewPool(base *Calculator, workers int) *Pool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	// the registers and macros are only read by the workers, until a line changes them and copies them for itself
+	base.shared = true
+	return &Pool{base: base, workers: workers}
+}
+
+// EvalBatch -> evaluate each line on its own copy of the base calculator, in parallel, returning the results in the
+// order of the lines. Lines not started when the context is done fail with its error.
+func (p *Pool) EvalBatch(ctx context.Context, lines []string) []Result {
+	results := make([]Result, len(lines))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker := &Calculator{pooled: true}
+			for i := range indices {
+				results[i] = worker.evalFrom(ctx, p.base, lines[i], p.Limits)
+			}
+		}()
+	}
+	for i := range lines {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return results
+}
+
+// evalFrom -> evaluate a line starting from the state of another calculator. A line that crashes fails on its own,
+// rather than taking the whole batch down from inside a worker.
+func (c *Calculator) evalFrom(ctx context.Context, base *Calculator, line string, limits Limits) (result Result) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.compiled = nil
+			result = Result{Stack: base.Stack(), Err: fmt.Errorf("Internal error: %v", r)}
+		}
+	}()
+	if !c.shared || c.compiled == nil {
+		// the code compiled for the last line may have used macros and registers it changed
+		c.compiled = make(map[compiledKey][]instruction)
+	}
+	c.stack = append([]Value(nil), base.stack...)
+	c.values, c.macros, c.shared = base.values, base.macros, true
+	c.mode, c.display, c.mixed, c.promote = base.mode, base.display, base.mixed, base.promote
+	c.precision, c.scale, c.rounding, c.sources = base.precision, base.scale, base.rounding, base.sources
+	c.operators, c.aliases, c.owned, c.custom = base.operators, base.aliases, false, base.custom
+	c.sandboxed, c.imports, c.plugins, c.unoptimized = base.sandboxed, base.imports, base.plugins, base.unoptimized
+	c.rng, c.seeded, c.randomSeed = nil, base.seeded, base.randomSeed
+	if c.seeded {
+		c.rng = rand.New(rand.NewSource(c.randomSeed))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Result{Stack: c.Stack(), Err: err}
+	}
+	if err := c.EvalContext(ctx, line, limits); err != nil {
+		return Result{Stack: c.Stack(), Err: err}
+	}
+	text, _ := c.result()
+	return Result{Stack: c.Stack(), Text: text}
+}
+
+// unshare -> copy the registers and macros the calculator shares with the calculators of a pool, before changing
+// them
+func (c *Calculator) unshare() {
+	if !c.shared {
+		return
+	}
+	values := make(map[string]Value, len(c.values))
+	for name, value := range c.values {
+		values[name] = value
+	}
+	macros := make(map[string][]string, len(c.macros))
+	for name, body := range c.macros {
+		macros[name] = body
+	}
+	c.values, c.macros, c.shared = values, macros, false
+}
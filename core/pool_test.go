@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// texts -> the result of each line of a batch, or its error
+func texts(results []Result) []string {
+	shown := make([]string, len(results))
+	for i, result := range results {
+		shown[i] = result.Text
+		if result.Err != nil {
+			shown[i] = "error: " + result.Err.Error()
+		}
+	}
+	return shown
+}
+
+func newBase(t *testing.T, lines ...string) *Calculator {
+	t.Helper()
+	base := New()
+	for _, line := range lines {
+		if err := base.Eval(line); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+	}
+	return base
+}
+
+func TestPoolOrder(t *testing.T) {
+	lines := make([]string, 200)
+	expected := make([]string, len(lines))
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%v dup *", i)
+		expected[i] = fmt.Sprint(i * i)
+	}
+	results := NewPool(New(), 8).EvalBatch(context.Background(), lines)
+	if got := texts(results); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
+
+func TestPoolShared(t *testing.T) {
+	base := newBase(t, "3 x=", "macro sq dup *")
+	results := NewPool(base, 4).EvalBatch(context.Background(), []string{"x sq", "4 sq", "x x +", "sq"})
+	expected := []string{"9", "16", "6", "error: Not enough items on the stack to perform this command: dup"}
+	if got := texts(results); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
+
+func TestPoolCopyOnWrite(t *testing.T) {
+	base := newBase(t, "3 x=", "macro sq dup *")
+	// a single worker runs every line, each from the state of the base rather than that left by the line before
+	lines := []string{"5 x= x", "x", "macro sq 0", "2 sq", "clv 1", "x sq"}
+	for _, workers := range []int{1, 4} {
+		results := NewPool(base, workers).EvalBatch(context.Background(), lines)
+		expected := []string{"5", "3", "", "4", "1", "9"}
+		if got := texts(results); strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("%v workers: got %v, expected %v", workers, got, expected)
+		}
+	}
+	if err := base.Eval("x sq"); err != nil || base.Show(base.Stack()[0]) != "9" {
+		t.Errorf("the base calculator changed: %v %v", base.Stack(), err)
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i, result := range NewPool(New(), 2).EvalBatch(ctx, []string{"1", "2", "3"}) {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("line %v: expected the context to be cancelled, got %v", i+1, result.Err)
+		}
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := NewPool(New(), 2).EvalBatch(ctx, []string{"0 1000000000 repeat ++", "0 1000000000 repeat ++"})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the batch took %v after its context was done", elapsed)
+	}
+	for i, result := range results {
+		if !errors.Is(result.Err, context.DeadlineExceeded) {
+			t.Errorf("line %v: expected the deadline to be exceeded, got %v", i+1, result.Err)
+		}
+	}
+}
+
+func TestPoolLimits(t *testing.T) {
+	pool := NewPool(New(), 2)
+	pool.Limits = Limits{Steps: 100}
+	results := pool.EvalBatch(context.Background(), []string{"1 2 +", "0 1000 repeat ++"})
+	var limit *LimitError
+	if results[0].Err != nil || !errors.As(results[1].Err, &limit) {
+		t.Errorf("got %v", texts(results))
+	}
+}
+
+func TestPoolCrash(t *testing.T) {
+	base := New()
+	err := base.Register(Op{Name: "boom", Fn: func([]Value) ([]Value, error) {
+		var elements []Value
+		return elements[:1], nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := NewPool(base, 2).EvalBatch(context.Background(), []string{"1", "boom", "2 3 +"})
+	got := texts(results)
+	if got[0] != "1" || !strings.HasPrefix(got[1], "error: Internal error:") || got[2] != "5" {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,279 @@
+package core
+
+import (
+	"math/big"
+	"strings"
+)
+
+// rational -> a fraction that isn't whole, kept exactly
+type rational struct {
+	value *big.Rat
+}
+
+// parseRational -> parse a fraction literal such as 3/4, which is an integer when the denominator divides the numerator
+func parseRational(item, mode string, digits int) (Value, bool) {
+	parts := strings.Split(item, "/")
+	if len(parts) != 2 {
+		return nil, false
+	}
+	num, ok1 := parseInteger(parts[0], mode)
+	den, ok2 := parseInteger(parts[1], mode)
+	if !ok1 || !ok2 || den.Sign() == 0 || strings.HasPrefix(parts[1], "-") || strings.HasPrefix(parts[1], "+") {
+		return nil, false
+	}
+	return rationalValue(new(big.Rat).SetFrac(num, den)), true
+}
+
+func (x rational) Kind() Kind {
+	return Rational
+}
+
+// String -> show a fraction as 3/2, or as 1 1/2 when showing mixed numbers
+func (x rational) show(c *Calculator) string {
+	num, den := x.value.Num(), x.value.Denom()
+	if !c.mixed || new(big.Int).Abs(num).Cmp(den) < 0 {
+		return c.formatInteger(num) + "/" + c.formatInteger(den)
+	}
+	whole, rest := new(big.Int).QuoRem(num, den, new(big.Int))
+	return c.formatInteger(whole) + " " + c.formatInteger(rest.Abs(rest)) + "/" + c.formatInteger(den)
+}
+
+func (x rational) Equal(other Value) bool {
+	return numericEqual(x, other)
+}
+
+// handleRational -> run a command exactly when its operands are integers and fractions, returning false
+// when it isn't an exact operation so that the operands are promoted to floats instead
+func (c *Calculator) handleRational(token Token) bool {
+	// dividing by zero gives an infinity or NaN like it does for floats
+	if token.Type == DIVIDE || token.Type == MOD {
+		if divisor, _ := exactValue(c.stack[len(c.stack)-1]); divisor.Sign() == 0 {
+			return false
+		}
+	}
+
+	switch token.Type {
+	case PLUS:
+		op1 := c.popRational(PLUS)
+		op2 := c.popRational(PLUS)
+		c.pushRational(new(big.Rat).Add(op2, op1))
+	case MINUS:
+		op1 := c.popRational(MINUS)
+		op2 := c.popRational(MINUS)
+		c.pushRational(new(big.Rat).Sub(op2, op1))
+	case MULTIPLY:
+		op1 := c.popRational(MULTIPLY)
+		op2 := c.popRational(MULTIPLY)
+		c.pushRational(new(big.Rat).Mul(op2, op1))
+	case DIVIDE:
+		op1 := c.popRational(DIVIDE)
+		op2 := c.popRational(DIVIDE)
+		c.pushRational(new(big.Rat).Quo(op2, op1))
+	case MOD:
+		op1 := c.popRational(MOD)
+		op2 := c.popRational(MOD)
+		quotient := new(big.Rat).SetInt(ratTrunc(new(big.Rat).Quo(op2, op1)))
+		c.pushRational(new(big.Rat).Sub(op2, quotient.Mul(quotient, op1)))
+	case DIVINT:
+		op1 := c.popRational(DIVINT)
+		op2 := c.popRational(DIVINT)
+		if op1.Sign() == 0 {
+			throwDivisionByZeroError(DIVINT)
+		}
+		c.pushInteger(ratTrunc(new(big.Rat).Quo(op2, op1)))
+	case POW:
+		// only whole powers of fractions are fractions
+		exponent, ok := c.stack[len(c.stack)-1].(integer)
+		if !ok || !exponent.value.IsInt64() {
+			return false
+		}
+		op1 := c.popInteger(POW)
+		op2 := c.popRational(POW)
+		if op1.Sign() < 0 {
+			if op2.Sign() == 0 {
+				throwDivisionByZeroError(POW)
+			}
+			op2 = new(big.Rat).Inv(op2)
+		}
+		n := new(big.Int).Abs(op1)
+		c.checkBits(float64(op2.Num().BitLen()+op2.Denom().BitLen()) * integerToFloat(n))
+		num := new(big.Int).Exp(op2.Num(), n, nil)
+		den := new(big.Int).Exp(op2.Denom(), n, nil)
+		c.pushRational(new(big.Rat).SetFrac(num, den))
+	case DECR:
+		op1 := c.popRational(DECR)
+		c.pushRational(new(big.Rat).Sub(op1, big.NewRat(1, 1)))
+	case INCR:
+		op1 := c.popRational(INCR)
+		c.pushRational(new(big.Rat).Add(op1, big.NewRat(1, 1)))
+	case NUM:
+		c.pushInteger(new(big.Int).Set(c.popRational(NUM).Num()))
+	case DEN:
+		c.pushInteger(new(big.Int).Set(c.popRational(DEN).Denom()))
+
+	case LT:
+		op1 := c.popRational(LT)
+		op2 := c.popRational(LT)
+		c.push(boolean(op2.Cmp(op1) < 0))
+	case LTOREQ:
+		op1 := c.popRational(LTOREQ)
+		op2 := c.popRational(LTOREQ)
+		c.push(boolean(op2.Cmp(op1) <= 0))
+	case NOTEQ:
+		op1 := c.popRational(NOTEQ)
+		op2 := c.popRational(NOTEQ)
+		c.push(boolean(op2.Cmp(op1) != 0))
+	case EQ:
+		op1 := c.popRational(EQ)
+		op2 := c.popRational(EQ)
+		c.push(boolean(op2.Cmp(op1) == 0))
+	case GT:
+		op1 := c.popRational(GT)
+		op2 := c.popRational(GT)
+		c.push(boolean(op2.Cmp(op1) > 0))
+	case GTOREQ:
+		op1 := c.popRational(GTOREQ)
+		op2 := c.popRational(GTOREQ)
+		c.push(boolean(op2.Cmp(op1) >= 0))
+
+	case CEIL:
+		op1 := c.popRational(CEIL)
+		ceil := ratFloor(op1.Neg(op1))
+		c.pushInteger(ceil.Neg(ceil))
+	case FLOOR:
+		c.pushInteger(ratFloor(c.popRational(FLOOR)))
+	case ROUND:
+		// halves round away from zero
+		op1 := c.popRational(ROUND)
+		half := new(big.Rat).Abs(op1)
+		rounded := ratFloor(half.Add(half, big.NewRat(1, 2)))
+		if op1.Sign() < 0 {
+			rounded.Neg(rounded)
+		}
+		c.pushInteger(rounded)
+	case IP:
+		c.pushInteger(ratTrunc(c.popRational(IP)))
+	case FP:
+		op1 := c.popRational(FP)
+		c.pushRational(new(big.Rat).Sub(op1, new(big.Rat).SetInt(ratTrunc(op1))))
+	case SIGN:
+		op1 := c.popRational(SIGN)
+		c.pushInteger(big.NewInt(int64(op1.Sign())))
+	case ABS:
+		c.pushRational(new(big.Rat).Abs(c.popRational(ABS)))
+	case MAX:
+		op1 := c.popRational(MAX)
+		op2 := c.popRational(MAX)
+		if op2.Cmp(op1) > 0 {
+			op1 = op2
+		}
+		c.pushRational(op1)
+	case MIN:
+		op1 := c.popRational(MIN)
+		op2 := c.popRational(MIN)
+		if op2.Cmp(op1) < 0 {
+			op1 = op2
+		}
+		c.pushRational(op1)
+	default:
+		return false
+	}
+	return true
+}
+
+// toFraction -> the closest fraction to x whose denominator is at most max, found from the continued fraction of x
+func toFraction(x *big.Rat, max *big.Int) *big.Rat {
+	// h and k hold the last two convergents h[1]/k[1] and h[0]/k[0]
+	h := [2]*big.Int{big.NewInt(0), big.NewInt(1)}
+	k := [2]*big.Int{big.NewInt(1), big.NewInt(0)}
+	rest := new(big.Rat).Set(x)
+	for {
+		a := ratFloor(rest)
+		next := new(big.Int).Add(new(big.Int).Mul(a, k[1]), k[0])
+		if next.Cmp(max) > 0 {
+			// the best approximation is either the last convergent or the largest semiconvergent that fits
+			n := new(big.Int).Quo(new(big.Int).Sub(max, k[0]), k[1])
+			semi := new(big.Rat).SetFrac(
+				new(big.Int).Add(h[0], new(big.Int).Mul(n, h[1])),
+				new(big.Int).Add(k[0], new(big.Int).Mul(n, k[1])))
+			last := new(big.Rat).SetFrac(h[1], k[1])
+			if distance(semi, x).Cmp(distance(last, x)) < 0 {
+				return semi
+			}
+			return last
+		}
+		h[0], h[1] = h[1], new(big.Int).Add(new(big.Int).Mul(a, h[1]), h[0])
+		k[0], k[1] = k[1], next
+		rest.Sub(rest, new(big.Rat).SetInt(a))
+		if rest.Sign() == 0 {
+			return new(big.Rat).SetFrac(h[1], k[1])
+		}
+		rest.Inv(rest)
+	}
+}
+
+func distance(a, b *big.Rat) *big.Rat {
+	d := new(big.Rat).Sub(a, b)
+	return d.Abs(d)
+}
+
+func ratFloor(x *big.Rat) *big.Int {
+	// the denominator is always positive, so Euclidean division rounds down
+	q, _ := new(big.Int).DivMod(x.Num(), x.Denom(), new(big.Int))
+	return q
+}
+
+func ratTrunc(x *big.Rat) *big.Int {
+	return new(big.Int).Quo(x.Num(), x.Denom())
+}
+
+// rationalValue -> a fraction as a value, which is an integer when it is whole
+func rationalValue(x *big.Rat) Value {
+	if x.IsInt() {
+		return integer{new(big.Int).Set(x.Num())}
+	}
+	return rational{x}
+}
+
+func (c *Calculator) pushRational(x *big.Rat) {
+	c.checkBits(float64(x.Num().BitLen() + x.Denom().BitLen()))
+	c.push(rationalValue(x))
+}
+
+func (c *Calculator) popRational(command string) *big.Rat {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	x, ok := exactValue(item)
+	if !ok {
+		throwWrongElementType(Rational, item.Kind())
+	}
+	return x
+}
+
+// exactValue -> the value of an integer, fraction or decimal as a big.Rat
+func exactValue(item Value) (*big.Rat, bool) {
+	switch x := item.(type) {
+	case integer:
+		return new(big.Rat).SetInt(x.value), true
+	case rational:
+		return new(big.Rat).Set(x.value), true
+	case decimal:
+		return new(big.Rat).Set(x.value), true
+	}
+	return nil, false
+}
+
+// ratValue -> the exact value of any finite number as a big.Rat
+func ratValue(item Value) (*big.Rat, bool) {
+	if x, ok := exactValue(item); ok {
+		return x, true
+	}
+	x, ok := bigValue(item, 0)
+	if !ok || x.IsInf() {
+		return nil, false
+	}
+	r, _ := x.Rat(nil)
+	return r, true
+}
@@ -0,0 +1,27 @@
+package core
+
+import "testing"
+
+func TestRational(t *testing.T) {
+	testResults(t, []resultTest{
+		{"1 3 / 3 *", "1"},
+		{"1 3 / 1 6 / +", "1/2"},
+		{"3/4 1/2 -", "1/4"},
+		{"2/3 1/3 /", "2"},
+		{"1/3 2 pow", "1/9"},
+		{"1/3 -2 pow", "9"},
+		{"2/4", "1/2"},
+		{"4/2", "2"},
+		{"2/3 3/4 <", "true"},
+		{"1/3 1/3 ==", "true"},
+		{"3/4 num", "3"},
+		{"3/4 den", "4"},
+		{"-7/2 sign", "-1"},
+		{"mixed 7/2", "3 1/2"},
+		{"mixed -7/2", "-3 1/2"},
+		{"3.14159 1000 ->q", "355/113"},
+		{"0.75 10 ->q", "3/4"},
+		// a float makes the result a float
+		{"1 3 / 0.5 +", "0.8333333333333333"},
+	})
+}
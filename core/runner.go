@@ -0,0 +1,189 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const testPrefix = "test:"
+
+// TestResult -> the outcome of a single test: block
+type TestResult struct {
+	File    string `json:"file"`
+	Name    string `json:"name"`
+	Line    int    `json:"line"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+type testCase struct {
+	name  string
+	pos   position
+	lines []scriptLine
+}
+
+// Test -> run every test in the *_test.rpn files under dir and report the results in the given format
+func Test(dir, format string, verbose bool) (bool, error) {
+	return New().Test(dir, format, verbose)
+}
+
+// Test -> run every test in the *_test.rpn files under dir with the commands and settings of this calculator
+func (c *Calculator) Test(dir, format string, verbose bool) (bool, error) {
+	var report func(io.Writer, []TestResult, bool)
+	switch format {
+	case "human":
+		report = reportHuman
+	case "json":
+		report = reportJSON
+	case "tap":
+		report = reportTAP
+	default:
+		return false, fmt.Errorf("Unknown test output format: %v", format)
+	}
+
+	results, err := c.RunTests(dir)
+	if err != nil {
+		return false, err
+	}
+	report(os.Stdout, results, verbose)
+
+	for _, result := range results {
+		if !result.Passed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RunTests -> discover the *_test.rpn files under dir and run each of their tests in a fresh calculator
+func RunTests(dir string) ([]TestResult, error) {
+	return New().RunTests(dir)
+}
+
+// RunTests -> run the tests under dir, each in a fresh calculator with the commands and settings of this one
+func (c *Calculator) RunTests(dir string) ([]TestResult, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(info.Name(), "_test.rpn") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TestResult, 0)
+	for _, file := range files {
+		lines, err := loadScript(file)
+		if err != nil {
+			return nil, err
+		}
+		setup, tests := splitTests(lines)
+		for _, test := range tests {
+			result := TestResult{File: file, Name: test.name, Line: test.pos.line, Passed: true}
+			f := c.fresh()
+			err := f.runScript(setup)
+			if err == nil {
+				err = f.runScript(test.lines)
+			}
+			if err != nil {
+				result.Passed = false
+				result.Message = err.Error()
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// splitTests -> separate the lines shared by every test from the test: blocks that follow them
+func splitTests(lines []scriptLine) ([]scriptLine, []testCase) {
+	var setup []scriptLine
+	var tests []testCase
+	for _, line := range lines {
+		if strings.HasPrefix(line.words[0], testPrefix) {
+			name := strings.TrimSpace(strings.TrimPrefix(strings.Join(line.words, " "), testPrefix))
+			tests = append(tests, testCase{name: name, pos: line.pos})
+			continue
+		}
+		if len(tests) == 0 {
+			setup = append(setup, line)
+		} else {
+			current := &tests[len(tests)-1]
+			current.lines = append(current.lines, line)
+		}
+	}
+	return setup, tests
+}
+
+func countPassed(results []TestResult) (passed, failed int) {
+	for _, result := range results {
+		if result.Passed {
+			passed++
+		} else {
+			failed++
+		}
+	}
+	return passed, failed
+}
+
+func reportHuman(w io.Writer, results []TestResult, verbose bool) {
+	for _, result := range results {
+		if result.Passed {
+			if verbose {
+				fmt.Fprintf(w, "--- PASS: %v (%v:%v)\n", result.Name, result.File, result.Line)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "--- FAIL: %v (%v:%v)\n", result.Name, result.File, result.Line)
+		fmt.Fprintf(w, "    %v\n", result.Message)
+	}
+
+	passed, failed := countPassed(results)
+	if len(results) == 0 {
+		fmt.Fprintln(w, "ok\tno tests to run")
+	} else if failed > 0 {
+		fmt.Fprintf(w, "FAIL\t%v passed, %v failed\n", passed, failed)
+	} else {
+		fmt.Fprintf(w, "ok\t%v passed\n", passed)
+	}
+}
+
+func reportJSON(w io.Writer, results []TestResult, verbose bool) {
+	passed, failed := countPassed(results)
+	summary := struct {
+		Tests  []TestResult `json:"tests"`
+		Passed int          `json:"passed"`
+		Failed int          `json:"failed"`
+	}{results, passed, failed}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(summary)
+}
+
+func reportTAP(w io.Writer, results []TestResult, verbose bool) {
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%v\n", len(results))
+	for i, result := range results {
+		status := "ok"
+		if !result.Passed {
+			status = "not ok"
+		}
+		fmt.Fprintf(w, "%v %v - %v: %v\n", status, i+1, result.File, result.Name)
+		if !result.Passed {
+			fmt.Fprintln(w, "  ---")
+			fmt.Fprintf(w, "  message: %q\n", result.Message)
+			fmt.Fprintf(w, "  at: %v:%v\n", result.File, result.Line)
+			fmt.Fprintln(w, "  ...")
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runnerDir -> a file of passing tests and a file of failing ones
+var runnerDir = filepath.Join("testdata", "runner")
+
+func TestSplitTests(t *testing.T) {
+	src := "macro sq dup *\n3 x=\n\ntest: first\nx sq 9 == assert\n\n# a comment\ntest: second one\n1\n2 +\ntest:\n"
+	setup, tests := splitTests(parseScript("s.rpn", src))
+	if len(setup) != 2 || setup[0].words[0] != "macro" || setup[1].words[0] != "3" {
+		t.Errorf("setup: %v", setup)
+	}
+	var got []string
+	for _, test := range tests {
+		got = append(got, fmt.Sprintf("%q at %v with %v lines", test.name, test.pos, len(test.lines)))
+	}
+	expected := []string{`"first" at s.rpn:4 with 1 lines`, `"second one" at s.rpn:8 with 2 lines`, `"" at s.rpn:11 with 0 lines`}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	fail := filepath.Join(runnerDir, "fail_test.rpn")
+	pass := filepath.Join(runnerDir, "pass_test.rpn")
+	failed := func(name string, line, at int, message string) TestResult {
+		return TestResult{File: fail, Name: name, Line: line, Message: fmt.Sprintf("%v:%v: %v", fail, at, message)}
+	}
+	passed := func(file, name string, line int) TestResult {
+		return TestResult{File: file, Name: name, Line: line, Passed: true}
+	}
+	expected := []TestResult{
+		failed("assert", 3, 4, "Assertion failed: expected true but found false"),
+		failed("assert=", 5, 6, "Assertion failed: expected 5 but found 4"),
+		failed("assert~", 7, 8, "Assertion failed: expected 1.4 ± 0.001 but found 1.4142135623730951"),
+		failed("assert on a number", 9, 10, "Expected a boolean on the stack but found an integer"),
+		failed("stops at the first failure", 11, 12, "Assertion failed: expected 2 but found 1"),
+		failed("unknown command", 13, 14, "Unknown command: frobnicate"),
+		passed(fail, "passes", 15),
+		passed(pass, "assert", 5),
+		passed(pass, "assert=", 7),
+		passed(pass, "assert~", 10),
+		passed(pass, "leaves items on the stack", 12),
+		passed(pass, "starts from a fresh calculator", 14),
+	}
+
+	results, err := RunTests(runnerDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("got %v results, expected %v: %v", len(results), len(expected), results)
+	}
+	for i, result := range results {
+		if result != expected[i] {
+			t.Errorf("got      %+v\nexpected %+v", result, expected[i])
+		}
+	}
+
+	// the unoptimised code fails in the same places
+	c := New()
+	c.DisableOptimizations()
+	if unoptimized, err := c.RunTests(runnerDir); err != nil || !reflect.DeepEqual(unoptimized, results) {
+		t.Errorf("without optimisations got %v, %v", unoptimized, err)
+	}
+}
+
+func TestRunTestsMissingDir(t *testing.T) {
+	if _, err := RunTests(filepath.Join("testdata", "missing")); err == nil {
+		t.Error("expected an error for a directory that doesn't exist")
+	}
+}
+
+// reportResults -> a passing test and a failing one
+var reportResults = []TestResult{
+	{File: "a_test.rpn", Name: "adds", Line: 1, Passed: true},
+	{File: "a_test.rpn", Name: "divides", Line: 3, Message: "a_test.rpn:4: Assertion failed: expected 2 but found 3"},
+}
+
+func TestReportJSON(t *testing.T) {
+	var out bytes.Buffer
+	reportJSON(&out, reportResults, false)
+	var summary struct {
+		Tests  []TestResult `json:"tests"`
+		Passed int          `json:"passed"`
+		Failed int          `json:"failed"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &summary); err != nil {
+		t.Fatalf("%v:\n%v", err, out.String())
+	}
+	if !reflect.DeepEqual(summary.Tests, reportResults) || summary.Passed != 1 || summary.Failed != 1 {
+		t.Errorf("got %+v", summary)
+	}
+	// a passing test has no message
+	if strings.Count(out.String(), `"message"`) != 1 {
+		t.Errorf("expected a message for the failing test only:\n%v", out.String())
+	}
+}
+
+func TestReportTAP(t *testing.T) {
+	var out bytes.Buffer
+	reportTAP(&out, reportResults, false)
+	expected := `TAP version 13
+1..2
+ok 1 - a_test.rpn: adds
+not ok 2 - a_test.rpn: divides
+  ---
+  message: "a_test.rpn:4: Assertion failed: expected 2 but found 3"
+  at: a_test.rpn:3
+  ...
+`
+	if out.String() != expected {
+		t.Errorf("got:\n%v\nexpected:\n%v", out.String(), expected)
+	}
+}
+
+func TestReportHuman(t *testing.T) {
+	tests := []struct {
+		results  []TestResult
+		verbose  bool
+		expected string
+	}{
+		{nil, false, "ok\tno tests to run\n"},
+		{reportResults[:1], false, "ok\t1 passed\n"},
+		{reportResults[:1], true, "--- PASS: adds (a_test.rpn:1)\nok\t1 passed\n"},
+		{reportResults, false, "--- FAIL: divides (a_test.rpn:3)\n    a_test.rpn:4: Assertion failed: expected 2 but found 3\nFAIL\t1 passed, 1 failed\n"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		reportHuman(&out, test.results, test.verbose)
+		if out.String() != test.expected {
+			t.Errorf("%v: got %q, expected %q", test.results, out.String(), test.expected)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Sandbox -> take away the commands of a calculator that act outside it, for running calculations that can't be
+// trusted. exit, disasm and the words of plugins are removed, no more plugins can be loaded, Calculate and Repl
+// don't read ~/.rpnrc, rand fails until seed has been run and import only reads scripts under the directories it is
+// given.
+func (c *Calculator) Sandbox(imports ...string) error {
+	c.imports = nil
+	for _, dir := range imports {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		resolved, err := resolvePath(dir)
+		if err != nil {
+			return err
+		}
+		// scripts are checked against the directories both before and after their links are followed
+		c.imports = append(c.imports, abs)
+		if resolved != abs {
+			c.imports = append(c.imports, resolved)
+		}
+	}
+	c.sandboxed = true
+	for _, name := range append([]string{EXIT, DISASM}, c.plugins...) {
+		if _, ok := c.keyword(name); ok {
+			c.Disable(name)
+		}
+	}
+	c.plugins = nil
+	return nil
+}
+
+// importScript -> run the lines of a script in the calculator, as if they had been typed in. A sandboxed
+// calculator checks that a script is in an allowed directory before looking for it, so that whether a file outside
+// them exists can't be found out.
+func (c *Calculator) importScript(Token) {
+	name := string(c.popString(IMPORT))
+	if abs, err := filepath.Abs(name); c.sandboxed && (err != nil || !c.importable(abs)) {
+		throw("Cannot import a script from outside the allowed directories in a sandbox: %v", IMPORT)
+	}
+	path, err := resolvePath(name)
+	if err != nil {
+		throw("%v: %v", err, IMPORT)
+	}
+	if c.sandboxed && !c.importable(path) {
+		throw("Cannot import a script from outside the allowed directories in a sandbox: %v", IMPORT)
+	}
+	if c.importing[path] {
+		throw("A script imports itself: %v", IMPORT)
+	}
+	lines, err := loadScript(path)
+	if err != nil {
+		throw("%v: %v", err, IMPORT)
+	}
+
+	if c.importing == nil {
+		c.importing = make(map[string]bool)
+	}
+	c.importing[path] = true
+	defer delete(c.importing, path)
+	for _, line := range lines {
+		if err := protect(func() { c.eval(line.words) }); err != nil {
+			if err == ErrExit {
+				panic(calcError{err})
+			}
+			throw("%v: %w", line.pos, err)
+		}
+	}
+}
+
+// importable -> whether a script is under one of the directories a sandboxed calculator can import from
+func (c *Calculator) importable(path string) bool {
+	for _, dir := range c.imports {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath -> the absolute path of a file with any symbolic links followed, so that a link can't lead out of an
+// allowed directory
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("Cannot find %v", path)
+	}
+	return resolved, nil
+}
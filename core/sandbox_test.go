@@ -0,0 +1,143 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSandbox(t *testing.T) {
+	c := New()
+	if err := c.LoadPlugin(plugin("calc")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Eval("macro sq dup *"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sandbox(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []resultTest{
+		{"exit", "error: Unknown command: exit"},
+		{"disasm sq", "error: Unknown command: disasm"},
+		{"1 echo", "error: Unknown command: echo"},
+		{"1 same", "error: Unknown command: same"},
+		{"rand", "error: Random numbers need a seed in a sandbox: rand"},
+		{"3 sq", "{ 9 }"},
+	}
+	for _, test := range tests {
+		c.stack = nil
+		if got := evalShown(c, test.line); got != test.expected {
+			t.Errorf("%q: got %v, expected %v", test.line, got, test.expected)
+		}
+	}
+
+	// rand gives the same numbers once it's seeded
+	seeded := New()
+	c.stack = nil
+	if err := c.Eval("42 seed rand rand"); err != nil {
+		t.Fatal(err)
+	}
+	if err := seeded.Eval("42 seed rand rand"); err != nil || !list(c.Stack()).Equal(list(seeded.Stack())) {
+		t.Errorf("got %v in a sandbox and %v outside it", c.Stack(), seeded.Stack())
+	}
+
+	if err := c.LoadPlugin(plugin("calc")); err == nil || err.Error() != plugin("calc")+": Cannot load plugins in a sandbox" {
+		t.Errorf("LoadPlugin: got %v", err)
+	}
+	help := c.Help()
+	for _, name := range []string{"exit", "disasm", "echo"} {
+		if listed(help, name) {
+			t.Errorf("%v is still in the help", name)
+		}
+	}
+	if problems := c.checkScript(parseScript("s.rpn", "1 exit")); len(problems) != 1 || problems[0].msg != "Unknown command: exit" {
+		t.Errorf("1 exit: got %v", problems)
+	}
+}
+
+// TestSandboxImport -> a sandbox only imports scripts under the allowed directories, wherever links lead
+func TestSandboxImport(t *testing.T) {
+	root, err := ioutil.TempDir("", "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	allowed, outside := filepath.Join(root, "allowed"), filepath.Join(root, "outside")
+	files := map[string]string{
+		filepath.Join(allowed, "ok.rpn"):     "1 2 +",
+		filepath.Join(outside, "secret.rpn"): "42",
+	}
+	for file, src := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		filepath.Join(allowed, "link.rpn"):  filepath.Join(outside, "secret.rpn"),
+		filepath.Join(allowed, "dir"):       outside,
+		filepath.Join(root, "allowed-link"): allowed,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("can't make symbolic links: %v", err)
+		}
+	}
+
+	refused := "error: Cannot import a script from outside the allowed directories in a sandbox: import"
+	tests := []resultTest{
+		{filepath.Join(allowed, "ok.rpn"), "{ 3 }"},
+		{filepath.Join(outside, "secret.rpn"), refused},
+		{filepath.Join(allowed, "..", "outside", "secret.rpn"), refused},
+		{filepath.Join(allowed, "link.rpn"), refused},
+		{filepath.Join(allowed, "dir", "secret.rpn"), refused},
+		// whether a file outside the allowed directories exists can't be found out
+		{filepath.Join(outside, "missing.rpn"), refused},
+		{filepath.Join(allowed, "missing.rpn"), "error: Cannot find " + filepath.Join(allowed, "missing.rpn") + ": import"},
+	}
+	// allowing a directory through a link allows the directory it leads to as well
+	for _, dir := range []string{allowed, filepath.Join(root, "allowed-link")} {
+		c := New()
+		if err := c.Sandbox(dir); err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range tests {
+			c.stack = nil
+			if got := evalShown(c, `"`+test.line+`" import`); got != test.expected {
+				t.Errorf("allowing %v, %v: got %v, expected %v", dir, test.line, got, test.expected)
+			}
+		}
+	}
+
+	// a path is checked before its links are followed, so one through a link to an allowed directory is only
+	// imported when the link is allowed too
+	through := `"` + filepath.Join(root, "allowed-link", "ok.rpn") + `" import`
+	c := New()
+	c.Sandbox(allowed)
+	if got := evalShown(c, through); got != refused {
+		t.Errorf("%v: got %v", through, got)
+	}
+	c = New()
+	c.Sandbox(filepath.Join(root, "allowed-link"))
+	if got := evalShown(c, through); got != "{ 3 }" {
+		t.Errorf("%v: got %v", through, got)
+	}
+
+	// a sandbox with no allowed directories imports nothing, and a calculator that isn't sandboxed anything
+	c = New()
+	if err := c.Sandbox(); err != nil {
+		t.Fatal(err)
+	}
+	if got := evalShown(c, `"`+filepath.Join(allowed, "ok.rpn")+`" import`); got != refused {
+		t.Errorf("no allowed directories: got %v", got)
+	}
+	if got := evalShown(New(), `"`+filepath.Join(allowed, "link.rpn")+`" import`); !strings.HasSuffix(got, "42 }") {
+		t.Errorf("outside a sandbox: got %v", got)
+	}
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"unicode"
+)
+
+// position -> a location in an rpn script
+type position struct {
+	file string
+	line int
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("%v:%v", p.file, p.line)
+}
+
+// scriptLine -> the commands on a single line of a script
+type scriptLine struct {
+	pos   position
+	words []string
+}
+
+func loadScript(path string) ([]scriptLine, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseScript(path, string(data)), nil
+}
+
+// parseScript -> split a script into lines of commands, dropping blank lines and # comments. A { or [ left open at
+// the end of a line carries the line on to the ones after it until it is closed, so long blocks and the macros
+// that use them can be spread over several lines.
+func parseScript(file, src string) []scriptLine {
+	var lines []scriptLine
+	var code []string
+	start, depth := 0, 0
+	for i, text := range strings.Split(src, "\n") {
+		if depth == 0 {
+			start, code = i, nil
+		}
+		text, _ = splitComment(text)
+		code = append(code, text)
+		if depth = nesting(depth, text); depth > 0 {
+			continue
+		}
+		if words := tokenize(strings.Join(code, "\n")); len(words) > 0 {
+			lines = append(lines, scriptLine{pos: position{file, start + 1}, words: words})
+		}
+	}
+	if words := tokenize(strings.Join(code, "\n")); depth > 0 && len(words) > 0 {
+		lines = append(lines, scriptLine{pos: position{file, start + 1}, words: words})
+	}
+	return lines
+}
+
+// nesting -> how many { and [ are still open at the end of a line of code, given how many were open at its start
+func nesting(depth int, code string) int {
+	quoted, escaped := false, false
+	for _, c := range code {
+		switch {
+		case quoted && escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case quoted:
+			quoted = c != '"'
+		case c == '"':
+			quoted = true
+		case c == '[' || c == '{':
+			depth++
+		case (c == ']' || c == '}') && depth > 0:
+			depth--
+		}
+	}
+	return depth
+}
+
+// tokenize -> split text into words at whitespace, keeping everything between [ and ] or { and } together as one word
+// and string literals between quotes exactly as they are written
+func tokenize(text string) []string {
+	var words []string
+	var word strings.Builder
+	depth := 0
+	quoted, escaped, space := false, false, false
+	for _, c := range text {
+		if quoted {
+			word.WriteRune(c)
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				quoted = false
+			}
+			continue
+		}
+		if unicode.IsSpace(c) {
+			if depth == 0 && word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			space = depth > 0
+			continue
+		}
+		if space {
+			word.WriteByte(' ')
+			space = false
+		}
+		switch {
+		case c == '"':
+			quoted = true
+		case c == '[' || c == '{':
+			depth++
+		case (c == ']' || c == '}') && depth > 0:
+			depth--
+		}
+		word.WriteRune(c)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// runScript -> evaluate a script line by line, reporting where it failed
+func (c *Calculator) runScript(lines []scriptLine) error {
+	for _, line := range lines {
+		if err := protect(func() { c.eval(line.words) }); err != nil {
+			return fmt.Errorf("%v: %v", line.pos, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// significant -> a measured number in sigfig mode, which knows the place of its last significant digit as a power of 10
+type significant struct {
+	value float64
+	lsd   int
+}
+
+// figures -> the number of significant figures, at least 1
+func (s significant) figures() int {
+	if s.value == 0 {
+		return 1
+	}
+	if n := magnitude(s.value) - s.lsd + 1; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// magnitude -> the power of 10 of the leading digit of x
+func magnitude(x float64) int {
+	return int(math.Floor(math.Log10(math.Abs(x))))
+}
+
+// withFigures -> x rounded to a number of significant figures
+func withFigures(x float64, figures int) significant {
+	if x == 0 || math.IsNaN(x) || math.IsInf(x, 0) {
+		return significant{x, 1 - figures}
+	}
+	return significant{x, magnitude(x) - figures + 1}
+}
+
+// parseSignificant -> parse a number literal in sigfig mode, counting its significant figures: 2.50 has three and 1200 has two
+func parseSignificant(item, mode string, digits int) (Value, bool) {
+	if mode != SIGFIG {
+		return nil, false
+	}
+	value, err := strconv.ParseFloat(item, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, false
+	}
+
+	mantissa, exponent := strings.TrimLeft(item, "+-"), 0
+	if i := strings.IndexAny(mantissa, "eE"); i >= 0 {
+		exponent, err = strconv.Atoi(mantissa[i+1:])
+		if err != nil {
+			return nil, false
+		}
+		mantissa = mantissa[:i]
+	}
+	if i := strings.Index(mantissa, "."); i >= 0 {
+		return significant{value, exponent - (len(mantissa) - i - 1)}, true
+	}
+	// trailing zeros of a whole number without a decimal point aren't significant
+	zeros := len(mantissa) - len(strings.TrimRight(mantissa, "0"))
+	if zeros == len(mantissa) {
+		zeros = 0
+	}
+	return significant{value, exponent + zeros}, true
+}
+
+func (s significant) Kind() Kind {
+	return Significant
+}
+
+func (s significant) show(c *Calculator) string {
+	return s.String()
+}
+
+// String -> a number rounded to its last significant digit, which is how it is shown in every mode
+func (s significant) String() string {
+	if s.lsd < 0 {
+		return strconv.FormatFloat(s.value, 'f', -s.lsd, 64)
+	}
+	unit := math.Pow(10, float64(s.lsd))
+	return strconv.FormatFloat(math.Round(s.value/unit)*unit, 'f', 0, 64)
+}
+
+// Equal -> whether a number with significant figures is equal to another number when both are rounded as they are shown
+func (s significant) Equal(other Value) bool {
+	y, ok := numberValue(other)
+	return ok && rounded(s, s.value) == rounded(other, y)
+}
+
+// handleSigfigs -> count the significant figures of a number
+func (c *Calculator) handleSigfigs(token Token) bool {
+	if token.Type != SIGFIGS {
+		return false
+	}
+	op1, exact := c.popSignificant(SIGFIGS)
+	if exact {
+		throwWrongElementType(Significant, Number)
+	}
+	c.pushInteger(big.NewInt(int64(op1.figures())))
+	return true
+}
+
+// handleSignificant -> run a command on numbers with significant figures, following the usual rules:
+// sums are as precise as their least precise operand and products have as many figures as the one with fewest.
+// Numbers without significant figures are exact.
+func (c *Calculator) handleSignificant(token Token) bool {
+	switch token.Type {
+	case PLUS, MINUS:
+		op1, exact1 := c.popSignificant(token.Type)
+		op2, exact2 := c.popSignificant(token.Type)
+		value := op2.value + op1.value
+		if token.Type == MINUS {
+			value = op2.value - op1.value
+		}
+		lsd := op2.lsd
+		if exact2 || (!exact1 && op1.lsd > lsd) {
+			lsd = op1.lsd
+		}
+		c.pushSignificant(significant{value, lsd})
+	case MULTIPLY, DIVIDE:
+		op1, exact1 := c.popSignificant(token.Type)
+		op2, exact2 := c.popSignificant(token.Type)
+		value := op2.value * op1.value
+		if token.Type == DIVIDE {
+			value = op2.value / op1.value
+		}
+		figures := op2.figures()
+		if exact2 || (!exact1 && op1.figures() < figures) {
+			figures = op1.figures()
+		}
+		c.pushSignificant(withFigures(value, figures))
+	case POW:
+		// the exponent is taken to be exact
+		op1, _ := c.popSignificant(POW)
+		op2, _ := c.popSignificant(POW)
+		c.pushSignificant(withFigures(math.Pow(op2.value, op1.value), op2.figures()))
+	case DECR, INCR:
+		op1, _ := c.popSignificant(token.Type)
+		step := 1.0
+		if token.Type == DECR {
+			step = -1
+		}
+		c.pushSignificant(significant{op1.value + step, op1.lsd})
+	case ABS:
+		op1, _ := c.popSignificant(ABS)
+		c.pushSignificant(significant{math.Abs(op1.value), op1.lsd})
+
+	case SQRT, SIN, COS, ATAN, ASIN, ACOS, SINH, COSH, TANH:
+		op1, _ := c.popSignificant(token.Type)
+		c.push(float(op1.value))
+		c.handleCommand(token)
+		c.pushSignificant(withFigures(c.popNumber(token.Type), op1.figures()))
+	case LN, LOG:
+		// a logarithm has as many decimal places as its operand has significant figures
+		op1, _ := c.popSignificant(token.Type)
+		c.push(float(op1.value))
+		c.handleCommand(token)
+		c.pushSignificant(significant{c.popNumber(token.Type), -op1.figures()})
+	case EXP:
+		// and the reverse for an exponential
+		op1, _ := c.popSignificant(EXP)
+		figures := -op1.lsd
+		if figures < 1 {
+			figures = 1
+		}
+		c.pushSignificant(withFigures(math.Exp(op1.value), figures))
+	default:
+		return false
+	}
+	return true
+}
+
+// rounded -> the value of a number, rounded to its last significant digit if it has one
+func rounded(item Value, x float64) float64 {
+	s, ok := item.(significant)
+	if !ok {
+		return x
+	}
+	number, _ := strconv.ParseFloat(s.String(), 64)
+	return number
+}
+
+func (c *Calculator) pushSignificant(s significant) {
+	c.push(s)
+}
+
+// popSignificant -> pop a number with significant figures, or an exact number
+func (c *Calculator) popSignificant(command string) (significant, bool) {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	if s, ok := item.(significant); ok {
+		return s, false
+	}
+	number, ok := numberValue(item)
+	if !ok {
+		throwWrongElementType(Number, item.Kind())
+	}
+	return significant{value: number}, true
+}
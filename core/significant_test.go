@@ -0,0 +1,22 @@
+package core
+
+import "testing"
+
+func TestSignificant(t *testing.T) {
+	testResults(t, []resultTest{
+		{"sigfig 2.50 sigfigs", "3"},
+		{"sigfig 0.00120 sigfigs", "3"},
+		{"sigfig 100 sigfigs", "1"},
+		{"sigfig 1.0e2 sigfigs", "2"},
+
+		// products have as many figures as the operand with fewest
+		{"sigfig 2.50 3.1 *", "7.8"},
+		{"sigfig 2.50 2 *", "5"},
+		{"sigfig 1.20 3 /", "0.4"},
+		{"sigfig 4.0 sqrt", "2.0"},
+
+		// sums are as precise as their least precise operand
+		{"sigfig 1.234 5.6 +", "6.8"},
+		{"sigfig 12.11 18.0 + 1.013 +", "31.1"},
+	})
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// text -> a string, which is shown quoted
+type text string
+
+// parseString -> parse a string literal such as "hello\n", with the escapes of a Go string
+func parseString(item, mode string, digits int) (Value, bool) {
+	if len(item) < 2 || !strings.HasPrefix(item, `"`) || !strings.HasSuffix(item, `"`) {
+		return nil, false
+	}
+	s, err := strconv.Unquote(item)
+	if err != nil {
+		return nil, false
+	}
+	return text(s), true
+}
+
+func (s text) Kind() Kind {
+	return String
+}
+
+func (s text) show(c *Calculator) string {
+	return strconv.Quote(string(s))
+}
+
+func (s text) Equal(other Value) bool {
+	t, ok := other.(text)
+	return ok && s == t
+}
+
+// placeholder -> a printf-style placeholder such as %v, %5d or %.2f
+var placeholder = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+// placeholderSize -> the width and precision of a placeholder
+var placeholderSize = regexp.MustCompile(`[0-9]+`)
+
+// handleString -> run the string commands, and compare strings
+func (c *Calculator) handleString(token Token) bool {
+	switch token.Type {
+	case CONCAT:
+		if len(c.stack) < 2 {
+			throwNotEnoughElementsError(CONCAT)
+		}
+		op1, _ := c.pop()
+		op2, _ := c.pop()
+		c.pushString(c.toString(op2) + c.toString(op1))
+	case LEN:
+		if !c.operandsAre(LEN, String) {
+			return false
+		}
+		c.pushInteger(big.NewInt(int64(utf8.RuneCountInString(string(c.popString(LEN))))))
+	case SUBSTR:
+		length := c.popNumber(SUBSTR)
+		start := c.popNumber(SUBSTR)
+		runes := []rune(c.popString(SUBSTR))
+		if start < 0 || length < 0 || start+length > float64(len(runes)) {
+			throw("Substring of %v characters from %v out of range for a string of %v characters: %v", length, start, len(runes), SUBSTR)
+		}
+		c.pushString(text(runes[int(start) : int(start)+int(length)]))
+	case UPPER:
+		c.pushString(text(strings.ToUpper(string(c.popString(UPPER)))))
+	case LOWER:
+		c.pushString(text(strings.ToLower(string(c.popString(LOWER)))))
+	case SPLIT:
+		separator := c.popString(SPLIT)
+		parts := strings.Split(string(c.popString(SPLIT)), string(separator))
+		elements := make(vector, len(parts))
+		for i, part := range parts {
+			elements[i] = text(part)
+		}
+		c.pushVector(elements)
+	case TOSTR:
+		item, err := c.pop()
+		if err != nil {
+			throwNotEnoughElementsError(TOSTR)
+		}
+		c.pushString(c.toString(item))
+	case FROMSTR:
+		s := strings.TrimSpace(string(c.popString(FROMSTR)))
+		number, err := c.getInput(s)
+		if err != nil {
+			throw("Not a number in %v mode: %v", strings.TrimSuffix(c.mode, " mode"), strconv.Quote(s))
+		}
+		c.push(number)
+	case FORMAT:
+		c.pushString(text(c.format(string(c.popString(FORMAT)))))
+	case EQ, NOTEQ:
+		if !c.anyOperand(token.Type, String) {
+			return false
+		}
+		op1, _ := c.pop()
+		op2, _ := c.pop()
+		c.push(boolean(op2.Equal(op1) == (token.Type == EQ)))
+	default:
+		return false
+	}
+	return true
+}
+
+// toString -> a string as it is, or any other item as it is shown on the stack
+func (c *Calculator) toString(item Value) text {
+	if s, ok := item.(text); ok {
+		return s
+	}
+	return text(item.show(c))
+}
+
+// format -> fill in the placeholders of a format string with items from the stack, the last placeholder taking the top item.
+// %v and %s show items as they are shown on the stack, %d, %x, %o and %b need integers and %f, %e and %g take any number.
+func (c *Calculator) format(layout string) string {
+	verbs := placeholder.FindAllString(layout, -1)
+	n := 0
+	for _, verb := range verbs {
+		if verb != "%%" {
+			n++
+		}
+	}
+	if len(c.stack) < n {
+		throwNotEnoughElementsError(FORMAT)
+	}
+	args := append([]Value(nil), c.stack[len(c.stack)-n:]...)
+	c.stack = c.stack[:len(c.stack)-n]
+
+	return placeholder.ReplaceAllStringFunc(layout, func(verb string) string {
+		if verb == "%%" {
+			return "%"
+		}
+		item := args[0]
+		args = args[1:]
+		for _, n := range placeholderSize.FindAllString(verb, -1) {
+			width, _ := strconv.ParseFloat(n, 64)
+			c.checkSize(width)
+		}
+		switch verb[len(verb)-1] {
+		case 'v', 's':
+			return fmt.Sprintf(verb[:len(verb)-1]+"s", string(c.toString(item)))
+		case 'd', 'x', 'X', 'o', 'b':
+			x, ok := exactValue(item)
+			if !ok {
+				if number, isNumber := numberValue(item); isNumber && number == math.Trunc(number) && !math.IsInf(number, 0) {
+					x, ok = new(big.Rat).SetFloat64(number), true
+				}
+			}
+			if !ok || !x.IsInt() {
+				throwWrongElementType(Integer, item.Kind())
+			}
+			return fmt.Sprintf(verb, x.Num())
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			if x, ok := item.(bigFloat); ok {
+				return fmt.Sprintf(verb, x.value)
+			}
+			number, ok := numberValue(item)
+			if !ok {
+				throwWrongElementType(Number, item.Kind())
+			}
+			return fmt.Sprintf(verb, number)
+		}
+		throw("Unknown placeholder %v: %v", verb, FORMAT)
+		return ""
+	})
+}
+
+func (c *Calculator) pushString(s text) {
+	c.push(s)
+}
+
+func (c *Calculator) popString(command string) text {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	s, ok := item.(text)
+	if !ok {
+		throwWrongElementType(String, item.Kind())
+	}
+	return s
+}
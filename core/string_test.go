@@ -0,0 +1,24 @@
+package core
+
+import "testing"
+
+func TestString(t *testing.T) {
+	testResults(t, []resultTest{
+		{`"abc" "def" concat`, `"abcdef"`},
+		{`"héllo" len`, "5"},
+		{`"héllo" 1 3 substr`, `"éll"`},
+		{`"Hi" upper`, `"HI"`},
+		{`"Hi" lower`, `"hi"`},
+		{`"a,b,c" "," split`, `["a" "b" "c"]`},
+		{`"a" "b" ==`, "false"},
+		{`1/2 ->str`, `"1/2"`},
+		{`"42" str-> 1 +`, "43"},
+		{`hex "ff" str-> dec`, "255"},
+		{`"ff" str->`, `error: Not a number in dec mode: "ff"`},
+		{`"hello" 3 5 substr`, "error: Substring of 5 characters from 3 out of range for a string of 5 characters: substr"},
+
+		// format shows its items with the current settings
+		{`3 2 / pi "%v is about %.2f" format`, `"3/2 is about 3.14"`},
+		{`"%v" format`, "error: Not enough items on the stack to perform this command: format"},
+	})
+}
@@ -0,0 +1,263 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Kind -> the type of a value on the stack
+type Kind int
+
+const (
+	Any Kind = iota
+	Number
+	Integer
+	Rational
+	Decimal
+	Complex
+	Interval
+	Measurement
+	Significant
+	BigFloat
+	Vector
+	Matrix
+	Polynomial
+	String
+	List
+	Word
+	Boolean
+	Unknown
+)
+
+// kindNames -> how each type of value is named in messages
+var kindNames = map[Kind]string{
+	Any:         "any",
+	Number:      "number",
+	Integer:     "integer",
+	Rational:    "rational",
+	Decimal:     "decimal number",
+	Complex:     "complex number",
+	Interval:    "interval",
+	Measurement: "measurement",
+	Significant: "number with significant figures",
+	BigFloat:    "arbitrary precision number",
+	Vector:      "vector",
+	Matrix:      "matrix",
+	Polynomial:  "polynomial",
+	String:      "string",
+	List:        "list",
+	Word:        "word",
+	Boolean:     "boolean",
+	Unknown:     "unknown",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// article -> the name of a type of value after "a" or "an", as it is used in messages
+func (k Kind) article() string {
+	name := kindNames[k]
+	if strings.IndexByte("aeiou", name[0]) >= 0 {
+		return "an " + name
+	}
+	return "a " + name
+}
+
+// Value -> an item on the stack. Each type of value shows itself as it is shown on the stack of a calculator, in its
+// mode and with its settings, and knows which values of other types are equal to it.
+type Value interface {
+	Kind() Kind
+	Equal(other Value) bool
+	show(c *Calculator) string
+}
+
+// reals -> the types of real number, which the other numeric types take as operands alongside their own
+var reals = []Kind{Number, Integer, Rational, Decimal, BigFloat}
+
+// realsAnd -> the types of real number along with another type
+func realsAnd(kind Kind) kindSet {
+	return setOf(reals...) | setOf(kind)
+}
+
+// kindSet -> a set of types of value, for checking the operands of a command without allocating
+type kindSet uint32
+
+func setOf(kinds ...Kind) kindSet {
+	var set kindSet
+	for _, kind := range kinds {
+		set |= 1 << uint(kind)
+	}
+	return set
+}
+
+func (s kindSet) has(kind Kind) bool {
+	return s&setOf(kind) != 0
+}
+
+// parsers -> how a word is read as each type of value, tried in order until one of them accepts it
+var parsers []func(item, mode string, digits int) (Value, bool)
+
+func init() {
+	parsers = append(parsers,
+		parseString,
+		parseList,
+		parsePolynomial,
+		parseVector,
+		parseSignificant,
+		parseDecimal,
+		parseComplex,
+		parseInterval,
+		parseRational,
+		parseReal,
+	)
+}
+
+// float -> a number held as a float64, the type every command works on when its operands have no better type
+type float float64
+
+func (x float) Kind() Kind {
+	return Number
+}
+
+func (x float) show(c *Calculator) string {
+	switch c.mode {
+	case DECIMAL:
+		return strconv.FormatFloat(float64(x), 'f', c.scale, 64)
+	case BIN:
+		return getBinary(float64(x))
+	case OCT:
+		return getOctal(float64(x))
+	case HEX:
+		return getHex(float64(x))
+	}
+	return strconv.FormatFloat(float64(x), 'f', -1, 64)
+}
+
+func (x float) Equal(other Value) bool {
+	if y, ok := other.(float); ok {
+		return x == y
+	}
+	return numericEqual(x, other)
+}
+
+// parseReal -> parse a number in the given mode, which is an integer if it is whole, or an arbitrary precision
+// number if the precision isn't 0
+func parseReal(item, mode string, digits int) (Value, bool) {
+	number, err := parseNumber(item, mode)
+	if err != nil {
+		return nil, false
+	}
+	if x, ok := parseInteger(item, mode); ok {
+		return integer{x}, true
+	}
+	if digits > 0 && !math.IsNaN(number) {
+		if x, ok := parseBig(item, mode, digits); ok {
+			return bigFloat{x}, true
+		}
+	}
+	return float(number), true
+}
+
+// numericEqual -> whether a real number is equal to another value, exactly when both are exact and otherwise at the
+// precision of the more precise one
+func numericEqual(x, other Value) bool {
+	switch other.(type) {
+	case significant, *measurement, complexNumber:
+		return other.Equal(x)
+	}
+	if a, ok := exactValue(x); ok {
+		if b, ok := exactValue(other); ok {
+			return a.Cmp(b) == 0
+		}
+	}
+	prec := uint(guardBits)
+	for _, item := range []Value{x, other} {
+		if y, ok := item.(bigFloat); ok && y.value.Prec()+guardBits > prec {
+			prec = y.value.Prec() + guardBits
+		}
+	}
+	a, ok1 := bigValue(x, prec)
+	b, ok2 := bigValue(other, prec)
+	return ok1 && ok2 && a.Cmp(b) == 0
+}
+
+// boolean -> the result of a comparison
+type boolean bool
+
+func (b boolean) Kind() Kind {
+	return Boolean
+}
+
+func (b boolean) show(c *Calculator) string {
+	return strconv.FormatBool(bool(b))
+}
+
+func (b boolean) Equal(other Value) bool {
+	c, ok := other.(boolean)
+	return ok && b == c
+}
+
+// unknown -> the result of a comparison that could go either way, such as of overlapping intervals
+type unknown struct{}
+
+func (u unknown) Kind() Kind {
+	return Unknown
+}
+
+func (u unknown) show(c *Calculator) string {
+	return "unknown"
+}
+
+func (u unknown) Equal(other Value) bool {
+	_, ok := other.(unknown)
+	return ok
+}
+
+// Float -> a number, for the commands of programs that embed a calculator
+func Float(x float64) Value {
+	return float(x)
+}
+
+// Int -> an integer, which is exact however large the numbers made from it get
+func Int(x int64) Value {
+	return integer{big.NewInt(x)}
+}
+
+// Text -> a string
+func Text(s string) Value {
+	return text(s)
+}
+
+// Bool -> a boolean
+func Bool(b bool) Value {
+	return boolean(b)
+}
+
+// AsFloat -> the value of any real number as a float64
+func AsFloat(item Value) (float64, bool) {
+	return numberValue(item)
+}
+
+// AsInt -> the value of an integer that fits in an int64
+func AsInt(item Value) (int64, bool) {
+	x, ok := item.(integer)
+	if !ok || !x.value.IsInt64() {
+		return 0, false
+	}
+	return x.value.Int64(), true
+}
+
+// AsText -> the contents of a string
+func AsText(item Value) (string, bool) {
+	s, ok := item.(text)
+	return string(s), ok
+}
+
+// AsBool -> the value of a boolean
+func AsBool(item Value) (bool, bool) {
+	b, ok := item.(boolean)
+	return bool(b), ok
+}
@@ -0,0 +1,234 @@
+package core
+
+import (
+	"math/big"
+	"strings"
+)
+
+// vector -> a vector, whose elements can be numbers of any type
+type vector []Value
+
+// parseVector -> parse a vector literal such as [1 2 3], whose elements are parsed like any other value,
+// or a matrix literal such as [[1 2] [3 4]]
+func parseVector(item, mode string, digits int) (Value, bool) {
+	if !strings.HasPrefix(item, "[") || !strings.HasSuffix(item, "]") {
+		return nil, false
+	}
+	elements := make(vector, 0)
+	for _, word := range tokenize(item[1 : len(item)-1]) {
+		element, err := parseValue(word, mode, digits)
+		if err != nil {
+			return nil, false
+		}
+		elements = append(elements, element)
+	}
+	if rows, ok := toMatrix(elements); ok {
+		return rows, true
+	}
+	return elements, true
+}
+
+func (v vector) Kind() Kind {
+	return Vector
+}
+
+func (v vector) show(c *Calculator) string {
+	shown := make([]string, len(v))
+	for i, element := range v {
+		shown[i] = element.show(c)
+	}
+	return "[" + strings.Join(shown, " ") + "]"
+}
+
+func (v vector) Equal(other Value) bool {
+	w, ok := other.(vector)
+	if !ok || len(v) != len(w) {
+		return false
+	}
+	for i, element := range v {
+		if !element.Equal(w[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleVector -> run the vector commands, and apply numeric commands to vectors element by element,
+// pairing each element with a scalar operand
+func (c *Calculator) handleVector(token Token) bool {
+	switch token.Type {
+	case TOVECTOR:
+		n := int(c.popNumber(TOVECTOR))
+		if n < 0 || len(c.stack) < n {
+			throwNotEnoughElementsError(TOVECTOR)
+		}
+		elements := append([]Value(nil), c.stack[len(c.stack)-n:]...)
+		c.stack = c.stack[:len(c.stack)-n]
+		c.pushVector(elements)
+	case FROMVECTOR:
+		elements := c.popVector(FROMVECTOR)
+		for _, element := range elements {
+			c.push(element)
+		}
+		c.pushInteger(big.NewInt(int64(len(elements))))
+	case LEN:
+		c.pushInteger(big.NewInt(int64(len(c.popVector(LEN)))))
+	case GET:
+		index := c.popNumber(GET)
+		elements := c.popVector(GET)
+		c.push(elements[checkIndex(elements, index, GET)])
+	case PUT:
+		value, err := c.pop()
+		if err != nil {
+			throwNotEnoughElementsError(PUT)
+		}
+		index := c.popNumber(PUT)
+		elements := append([]Value(nil), c.popVector(PUT)...)
+		elements[checkIndex(elements, index, PUT)] = value
+		c.pushVector(elements)
+	case SUM:
+		c.push(c.sum(c.popVector(SUM)))
+	case DOT:
+		op1 := c.popVector(DOT)
+		op2 := c.popVector(DOT)
+		c.push(c.dot(op2, op1))
+	case CROSS:
+		op1 := c.popVector(CROSS)
+		op2 := c.popVector(CROSS)
+		if len(op1) != 3 || len(op2) != 3 {
+			throw("The cross product needs vectors with 3 elements: %v", CROSS)
+		}
+		c.pushVector([]Value{
+			c.compute(MINUS, c.compute(MULTIPLY, op2[1], op1[2]), c.compute(MULTIPLY, op2[2], op1[1])),
+			c.compute(MINUS, c.compute(MULTIPLY, op2[2], op1[0]), c.compute(MULTIPLY, op2[0], op1[2])),
+			c.compute(MINUS, c.compute(MULTIPLY, op2[0], op1[1]), c.compute(MULTIPLY, op2[1], op1[0])),
+		})
+	case NORM:
+		op1 := c.popVector(NORM)
+		c.push(c.compute(SQRT, c.dot(op1, op1)))
+	case UNIT:
+		op1 := c.popVector(UNIT)
+		norm := c.compute(SQRT, c.dot(op1, op1))
+		c.pushVector(c.elementwise(DIVIDE, op1, []Value{norm}))
+	case EQ, NOTEQ:
+		if !c.anyOperand(token.Type, Vector) {
+			return false
+		}
+		op1, _ := c.pop()
+		op2, _ := c.pop()
+		c.push(boolean(op2.Equal(op1) == (token.Type == EQ)))
+	default:
+		return c.broadcast(token)
+	}
+	return true
+}
+
+// broadcast -> apply a command that takes and gives numbers to the elements of its vector operands
+func (c *Calculator) broadcast(token Token) bool {
+	effect := effects[token.Type]
+	if !effect.numeric() || !c.anyOperand(token.Type, Vector) {
+		return false
+	}
+
+	switch len(effect.in) {
+	case 1:
+		op1 := c.popVector(token.Type)
+		c.pushVector(c.elementwise(token.Type, op1))
+	case 2:
+		op1 := c.popElements(token.Type)
+		op2 := c.popElements(token.Type)
+		c.pushVector(c.elementwise(token.Type, op2, op1))
+	default:
+		return false
+	}
+	return true
+}
+
+// elementwise -> run a command on the matching elements of vectors, where a single element is used with every element
+func (c *Calculator) elementwise(command string, operands ...[]Value) []Value {
+	length := 1
+	for _, operand := range operands {
+		if len(operand) != 1 {
+			if length != 1 && len(operand) != length {
+				throw("Vectors have different lengths: %v", command)
+			}
+			length = len(operand)
+		}
+	}
+
+	result := make([]Value, length)
+	for i := range result {
+		args := make([]Value, len(operands))
+		for j, operand := range operands {
+			if len(operand) == 1 {
+				args[j] = operand[0]
+			} else {
+				args[j] = operand[i]
+			}
+		}
+		result[i] = c.compute(command, args...)
+	}
+	return result
+}
+
+// compute -> run a command on some operands away from the stack, returning its single result
+func (c *Calculator) compute(command string, args ...Value) Value {
+	saved := c.stack
+	defer func() { c.stack = saved }()
+	c.stack = append([]Value(nil), args...)
+	c.handleCommand(Token{Type: command})
+	if len(c.stack) != 1 {
+		throw("Expected a single result from %v", command)
+	}
+	return c.stack[0]
+}
+
+func (c *Calculator) sum(elements []Value) Value {
+	var total Value = integer{new(big.Int)}
+	for _, element := range elements {
+		total = c.compute(PLUS, total, element)
+	}
+	return total
+}
+
+func (c *Calculator) dot(a, b []Value) Value {
+	if len(a) != len(b) {
+		throw("Vectors have different lengths: %v", DOT)
+	}
+	return c.sum(c.elementwise(MULTIPLY, a, b))
+}
+
+func checkIndex(elements []Value, index float64, command string) int {
+	if index < 0 || int(index) >= len(elements) {
+		throw("Index %v out of range for a vector of %v elements: %v", index, len(elements), command)
+	}
+	return int(index)
+}
+
+func (c *Calculator) pushVector(elements vector) {
+	c.push(elements)
+}
+
+func (c *Calculator) popVector(command string) vector {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	elements, ok := item.(vector)
+	if !ok {
+		throwWrongElementType(Vector, item.Kind())
+	}
+	return elements
+}
+
+// popElements -> pop a vector's elements, or a scalar as a single element
+func (c *Calculator) popElements(command string) []Value {
+	item, err := c.pop()
+	if err != nil {
+		throwNotEnoughElementsError(command)
+	}
+	if elements, ok := item.(vector); ok {
+		return elements
+	}
+	return []Value{item}
+}
@@ -0,0 +1,26 @@
+package core
+
+import "testing"
+
+func TestVector(t *testing.T) {
+	testResults(t, []resultTest{
+		{"[1 2] [3 4] +", "[4 6]"},
+		{"[1 2] 2 *", "[2 4]"},
+		{"1 [1 2] -", "[0 -1]"},
+		{"[1 2] 2 pow", "[1 4]"},
+		{"[1/3 2/3] 3 *", "[1 2]"},
+		{"[1 2 3] [4 5 6] dot", "32"},
+		{"[1 2 3] [4 5 6] cross", "[-3 6 -3]"},
+		{"[3 4] norm", "5"},
+		{"[3 4] unit", "[0.6 0.8]"},
+		{"[1 2 3] sum", "6"},
+		{"[1 2 3] len", "3"},
+		{"[1 2 3] 1 get", "2"},
+		{"[1 2 3] 1 9 put", "[1 9 3]"},
+		{"1 2 3 3 ->v", "[1 2 3]"},
+		{"[1 2 3] v->", "1, 2, 3, 3"},
+		{"[1 2] [1 2 3] +", "error: Vectors have different lengths: +"},
+		{"[1 2] [1 2 3] dot", "error: Vectors have different lengths: dot"},
+		{"[1 2 3] 3 get", "error: Index 3 out of range for a vector of 3 elements: get"},
+	})
+}
@@ -0,0 +1,72 @@
+package core
+
+// run -> execute compiled instructions
+func (c *Calculator) run(code []instruction) {
+	for i := range code {
+		in := &code[i]
+		c.step()
+		switch in.op {
+		case opPush:
+			c.push(in.value)
+		case opCommand:
+			c.runCommand(in.command)
+		case opWord:
+			c.runWord(in)
+		case opRepeat:
+			n := c.popNumber(REPEAT)
+			for j := 0; j < int(n); j++ {
+				c.run(in.body)
+			}
+		case opDefine:
+			c.define(in.name, in.words)
+		case opEval:
+			c.eval(in.words)
+		case opCheck:
+			if len(c.stack) < in.depth {
+				throwNotEnoughElementsError(in.name)
+			}
+		}
+	}
+}
+
+// runWord -> resolve a word the same way ParseToken does: as a number, then a register, then a macro
+func (c *Calculator) runWord(in *instruction) {
+	if c.precision > 0 {
+		if number, err := parseValue(in.name, c.mode, c.precision); err == nil {
+			c.push(number)
+			return
+		}
+	} else if i := modeIndex(c.mode); in.parsed[i] {
+		c.push(in.numbers[i])
+		return
+	}
+	if x, ok := c.values[in.name]; ok {
+		c.push(x)
+		return
+	}
+	if _, ok := c.macros[in.name]; ok {
+		c.runMacro(in.name)
+		return
+	}
+	throw("Unknown command: %v", in.name)
+}
+
+// runMacro -> run a macro, compiling it the first time it's called in the current mode and precision
+func (c *Calculator) runMacro(name string) {
+	key := compiledKey{name, c.mode, c.precision}
+	code, ok := c.compiled[key]
+	if !ok {
+		code = c.compile(c.macros[name], c.mode, c.precision)
+		c.compiled[key] = code
+	}
+	c.calls++
+	defer func() { c.calls-- }()
+	c.checkRecursion()
+	c.run(code)
+}
+
+func (c *Calculator) define(name string, body []string) {
+	c.unshare()
+	c.macros[name] = body
+	c.compiled = make(map[compiledKey][]instruction)
+}
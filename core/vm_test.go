@@ -0,0 +1,40 @@
+package core
+
+import "testing"
+
+// loop -> a macro run over a thousand rows, with a constant to fold and a pair of commands that cancel out
+const loop = "dup dup * 2 pi * + sqrt swap swap + 1000 %"
+
+func benchmarkLoop(b *testing.B, c *Calculator) {
+	if err := c.Eval("macro step " + loop); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.Eval("clr 1 1000 repeat step"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMacroCompiled(b *testing.B) {
+	benchmarkLoop(b, New())
+}
+
+func BenchmarkMacroUnoptimized(b *testing.B) {
+	c := New()
+	c.DisableOptimizations()
+	benchmarkLoop(b, c)
+}
+
+// BenchmarkMacroInterpreted -> the same loop run by eval, parsing each word of the macro every time it runs
+func BenchmarkMacroInterpreted(b *testing.B) {
+	c := New()
+	body := tokenize(loop)
+	for i := 0; i < b.N; i++ {
+		c.stack = []Value{float(1)}
+		for j := 0; j < 1000; j++ {
+			c.eval(body)
+		}
+	}
+}
@@ -1,84 +0,0 @@
-package help
-
-const COMMANDHELP = `
-	+  = "plus"
-	-  = "minus"
-	*  = "multiply"
-	/  = "divide"
-	!  = "not"
-	!= = "not equal to"
-	%  = "modulus"
-	-- = "decrement"
-	++ = "increment"
-
-	rand = "rand"
-
-	clr = "clear stack"
-	clv = "clear values"
-	cla = "clear stack and values"
-
-	&  = "bit and"
-	|  = "bit or"
-	^  = "bit xor"
-	~  = "bit not"
-	<< = "bit shift left"
-	>> = "bit shift right"
-
-	&& = "bool and"
-	|| = "bool or"
-	^^ = "bool xor"
-
-	<  = "less than"
-	<= = "less than or equal to"
-	== = "equal to"
-	>  = "greater than"
-	>= = "greater than or equal to"
-
-	acos  = "acos"
-	asign = "asin"
-	atan  = "atan"
-	cos   = "cos"
-	cosh  = "cosh"
-	sin   = "sin"
-	sinh  = "sinh"
-	tanh  = "tanh"
-
-	ceil  = "ceiling"
-	floor = "floor"
-	round = "round"
-	ip    = "integer part"
-	fp    = "floating part"
-	sign  = "push -1, 0 or 0 depending on the sign"
-	abs   = "absolute value"
-	max   = "min"
-	min   = "max"
-
-	hex = "hex mode"
-	dec = "dec mode"
-	bin = "bin mode"
-	oct = "oct mode"
-
-	exp  = "exponential"
-	fact = "factorial"
-	sqrt = "square root"
-	ln   = "natural log"
-	log  = "logarithm"
-	pow  = "raise a number to a power"
-
-	pick   = "pick nth item from the stack"
-	repeat = "repeat an operation n times"
-	depth  = "push current stack depth"
-	drop   = "drop top item from the stack"
-	dropn  = "drop n items from the stack"
-	dup    = "duplicate top stack item"
-	dupn   = "duplicate top n stack items in order"
-	roll   = "roll stack upwords by n"
-	rolld  = "roll stack downwards by n"
-	stack  = "toggle stack display from horizontal to vertical"
-	swap   = "swap top 2 stack items"
-
-	macro = "define a macro"
-	x=    = "assign a value to the x register"
-
-	exit = "exit"
-`